@@ -14,8 +14,12 @@
 package apistructs
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"gorm.io/gorm"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -26,6 +30,16 @@ const EventProcessingStatus EventStatus = "processing"
 const EventProcessFailedStatus EventStatus = "processFailed"
 const EventProcessedStatus EventStatus = "processed"
 
+// EventPriorityLabel 事件标签中的优先级, 覆盖触发器中声明的流水线优先级
+const EventPriorityLabel = "priority"
+
+// ReservedEventNamePrefix server 发布的事件名称前缀, 用户和 webhook 不能发送这个前缀的事件
+const ReservedEventNamePrefix = "eventops."
+
+func IsReservedEventName(name string) bool {
+	return strings.HasPrefix(name, ReservedEventNamePrefix)
+}
+
 type Value string
 
 type FileValueType string
@@ -45,6 +59,33 @@ type Event struct {
 
 	Timestamp    int64    `json:"timestamp"`
 	SupportUsers []string `json:"users"`
+
+	// webhook 的原始请求内容, 触发器可以使用 payload.xxx 取值
+	Payload json.RawMessage `json:"payload,omitempty" yaml:"-"`
+
+	// 相同创建人和名称的事件 idempotencyKey 相同只会保存一次
+	IdempotencyKey string `json:"idempotencyKey,omitempty" yaml:"idempotencyKey,omitempty"`
+
+	// 流水线结束事件触发流水线的串联深度, 超过最大深度后不再发布事件, 只能由 server 设置
+	ChainDepth int `json:"chainDepth,omitempty" yaml:"-"`
+}
+
+type SendEventResponse struct {
+	Id uint64 `json:"id"`
+	// 为 true 的时候 id 是之前已经保存的事件
+	Duplicate bool `json:"duplicate"`
+}
+
+// ContentHash 去掉时间戳和 idempotencyKey 后的事件内容 hash, 用于重复事件判断
+func (event Event) ContentHash() (string, error) {
+	event.Timestamp = 0
+	event.IdempotencyKey = ""
+
+	content, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(content)), nil
 }
 
 type EventDetail struct {
@@ -71,6 +112,12 @@ func (event Event) Check() error {
 	if event.Timestamp <= 0 {
 		return fmt.Errorf("event timestamp can not empty")
 	}
+	if event.ChainDepth < 0 {
+		return fmt.Errorf("event chainDepth can not less than 0")
+	}
+	if len(event.IdempotencyKey) > 255 {
+		return fmt.Errorf("event idempotencyKey length can not greater than 255")
+	}
 	for key, value := range event.Values {
 		if key == "" {
 			return fmt.Errorf("event values key can not empty")
@@ -100,5 +147,10 @@ func (event Event) Check() error {
 			return fmt.Errorf("event labels key %v type can not empty", key)
 		}
 	}
+	if value, ok := event.Labels[EventPriorityLabel]; ok {
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("event labels key %v value should be integer", EventPriorityLabel)
+		}
+	}
 	return nil
 }
@@ -46,13 +46,24 @@ type PipelineStatus string
 
 const PipelineRunningStatus PipelineStatus = "running"
 
+// PipelineQueuedStatus 同一个并发组中有流水线在运行, 排队等待执行
+const PipelineQueuedStatus PipelineStatus = "queued"
+
+// PipelinePausedStatus 运行中的任务继续执行, 但是不再创建新的任务, 服务重启后不会自动恢复
+const PipelinePausedStatus PipelineStatus = "paused"
+
 const PipelineSuccessStatus PipelineStatus = "success"
 const PipelineFailedStatus PipelineStatus = "failed"
 const PipelineCancelStatus PipelineStatus = "cancel"
+const PipelineTimeoutStatus PipelineStatus = "timeout"
+
+var EndPipelineStatuses = []PipelineStatus{PipelineSuccessStatus, PipelineFailedStatus, PipelineCancelStatus, PipelineTimeoutStatus}
 
 func (status PipelineStatus) IsEnd() bool {
-	if status == PipelineSuccessStatus || status == PipelineFailedStatus || status == PipelineCancelStatus {
-		return true
+	for _, endStatus := range EndPipelineStatuses {
+		if status == endStatus {
+			return true
+		}
 	}
 	return false
 }
@@ -71,10 +82,33 @@ func (status PipelineStatus) IsRunning() bool {
 	return false
 }
 
+func (status PipelineStatus) IsQueued() bool {
+	if status == PipelineQueuedStatus {
+		return true
+	}
+	return false
+}
+
+func (status PipelineStatus) IsPaused() bool {
+	if status == PipelinePausedStatus {
+		return true
+	}
+	return false
+}
+
 type PipelineDetail struct {
 	Pipeline      Pipeline      `json:"pipeline"`
 	PipelineExtra PipelineExtra `json:"pipelineExtra"`
 	Tasks         []Task        `json:"tasks"`
+	// Schedule 排队中的流水线的调度信息
+	Schedule *PipelineSchedule `json:"schedule,omitempty"`
+}
+
+type PipelineSchedule struct {
+	Priority int `json:"priority"`
+	// Position 在排队的流水线中的执行顺序, 从 1 开始
+	Position int    `json:"position"`
+	Reason   string `json:"reason"`
 }
 
 type Pipeline struct {
@@ -90,6 +124,8 @@ type Pipeline struct {
 	CostTimeSec         uint64         `json:"costTimeSec"`
 	TimeBegin           *time.Time     `json:"timeBegin"`
 	TimeEnd             *time.Time     `json:"timeEnd"`
+	ConcurrencyGroup    string         `json:"concurrencyGroup,omitempty"`
+	Priority            int            `json:"priority"`
 
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
@@ -111,6 +147,16 @@ type PipelineExtra struct {
 
 type PipelineExtraInfo struct {
 	StopReason string `json:"stopReason"`
+	RerunFrom  uint64 `json:"rerunFrom,omitempty"`
+	// FinallyStatus finally 任务的执行结果, 不影响流水线的状态
+	FinallyStatus TaskStatus `json:"finallyStatus,omitempty"`
+	FinallyError  string     `json:"finallyError,omitempty"`
+	// Warnings 设置了 allowFailure 的任务失败时记录在这里, 流水线状态仍然可以是成功
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+type RerunPipelineBody struct {
+	FromFailed bool `json:"fromFailed"`
 }
 
 type PipelineExtraContents struct {
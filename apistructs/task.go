@@ -20,13 +20,14 @@ import (
 type TaskType string
 
 const (
-	K8sType    TaskType = "k8s"
-	DockerType TaskType = "docker"
-	OsType     TaskType = "os"
-	PipeType   TaskType = "pipeline"
+	K8sType      TaskType = "k8s"
+	DockerType   TaskType = "docker"
+	OsType       TaskType = "os"
+	PipeType     TaskType = "pipeline"
+	ApprovalType TaskType = "approval"
 )
 
-var TaskTypeList = []TaskType{K8sType, DockerType, OsType, PipeType}
+var TaskTypeList = []TaskType{K8sType, DockerType, OsType, PipeType, ApprovalType}
 
 func (t TaskType) String() string {
 	return string(t)
@@ -48,6 +49,7 @@ type TaskStatus string
 const InitTaskStatus TaskStatus = "initializing"
 const CreatedTaskStatus TaskStatus = "created"
 const RunningTaskStatus TaskStatus = "running"
+const WaitApprovalTaskStatus TaskStatus = "waitApproval"
 
 const CancelTaskStatus TaskStatus = "cancel"
 const FailedTaskStatus TaskStatus = "failed"
@@ -55,9 +57,11 @@ const SuccessTaskStatus TaskStatus = "success"
 const UnKnowTaskStatus TaskStatus = "unknow"
 const ErrorTaskStatus TaskStatus = "error"
 const TimeoutTaskStatus TaskStatus = "timeout"
+const SkippedTaskStatus TaskStatus = "skipped"
+const RejectedTaskStatus TaskStatus = "rejected"
 
-var DoneTaskStatuses = []TaskStatus{SuccessTaskStatus, FailedTaskStatus, CancelTaskStatus, UnKnowTaskStatus, ErrorTaskStatus, TimeoutTaskStatus}
-var FailedTaskStatuses = []TaskStatus{FailedTaskStatus, CancelTaskStatus, UnKnowTaskStatus, ErrorTaskStatus, TimeoutTaskStatus}
+var DoneTaskStatuses = []TaskStatus{SuccessTaskStatus, FailedTaskStatus, CancelTaskStatus, UnKnowTaskStatus, ErrorTaskStatus, TimeoutTaskStatus, SkippedTaskStatus, RejectedTaskStatus}
+var FailedTaskStatuses = []TaskStatus{FailedTaskStatus, CancelTaskStatus, UnKnowTaskStatus, ErrorTaskStatus, TimeoutTaskStatus, RejectedTaskStatus}
 
 func (taskStatus TaskStatus) IsDoneStatus() bool {
 	for _, status := range DoneTaskStatuses {
@@ -91,16 +95,47 @@ type Task struct {
 	TimeBegin    *time.Time `json:"timeBegin"`
 	TimeEnd      *time.Time `json:"timeEnd"`
 	Creater      string     `json:"creater"`
+	Cleaned      bool       `json:"cleaned"`
 
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
 type TaskExtra struct {
-	Error     string   `json:"error"`
-	ChooseTag string   `json:"chooseTag"`
-	Inputs    Inputs   `json:"inputs"`
-	Contexts  Contexts `json:"contexts"`
+	Error     string            `json:"error"`
+	ChooseTag string            `json:"chooseTag"`
+	Inputs    Inputs            `json:"inputs"`
+	Contexts  Contexts          `json:"contexts"`
+	Attempts  []TaskAttempt     `json:"attempts"`
+	Approval  *TaskApproval     `json:"approval,omitempty"`
+	Matrix    map[string]string `json:"matrix,omitempty"`
+}
+
+type ApprovalResult string
+
+const ApprovedResult ApprovalResult = "approved"
+const RejectedResult ApprovalResult = "rejected"
+
+type TaskApproval struct {
+	Approvers []string       `json:"approvers"`
+	Message   string         `json:"message"`
+	Result    ApprovalResult `json:"result"`
+	Operator  string         `json:"operator"`
+	Comment   string         `json:"comment"`
+	Time      *time.Time     `json:"time"`
+}
+
+type ApproveTaskBody struct {
+	Comment string `json:"comment"`
+}
+
+type TaskAttempt struct {
+	Attempt   int        `json:"attempt"`
+	Status    TaskStatus `json:"status"`
+	Error     string     `json:"error"`
+	JobSign   string     `json:"jobSign"`
+	TimeBegin *time.Time `json:"timeBegin"`
+	TimeEnd   *time.Time `json:"timeEnd"`
 }
 
 type Inputs map[string]Input
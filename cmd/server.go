@@ -89,7 +89,7 @@ func (srv *server) run() {
 }
 
 func (srv *server) ListenShutdown(server *http.Server) {
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
 	<-quit
 	log.Println("Shutdown Server ...")
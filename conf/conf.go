@@ -34,6 +34,7 @@ type Config struct {
 	Event           Event    `yaml:"event"`
 	Actuator        Actuator `yaml:"actuator"`
 	Minio           Minio    `yaml:"minio"`
+	Pipeline        Pipeline `yaml:"pipeline"`
 }
 
 type Uc struct {
@@ -43,7 +44,8 @@ type Uc struct {
 }
 
 type Auth struct {
-	WhiteUrlList []string
+	WhiteUrlList       []string
+	WhiteUrlPrefixList []string
 }
 
 type Actuator struct {
@@ -51,7 +53,23 @@ type Actuator struct {
 }
 
 type Event struct {
-	Process Process `yaml:"process"`
+	Process  Process  `yaml:"process"`
+	Schedule Schedule `yaml:"schedule"`
+	Dedup    Dedup    `yaml:"dedup"`
+	// 流水线结束事件的最大串联深度, 0 代表不发布流水线结束事件
+	MaxChainDepth int `default:"5" env:"EVENTOPS_EVENT_MAX_CHAIN_DEPTH" yaml:"maxChainDepth"`
+}
+
+type Dedup struct {
+	// 内容 hash 去重的时间窗口(秒), 0 代表不限制, idempotencyKey 相同的事件始终只保存一次
+	Window int64 `default:"86400" env:"EVENTOPS_DEDUP_WINDOW" yaml:"window"`
+	// 没有 idempotencyKey 的事件是否使用内容 hash 去重
+	ContentHash bool `default:"false" env:"EVENTOPS_DEDUP_CONTENT_HASH" yaml:"contentHash"`
+}
+
+type Schedule struct {
+	Enable       bool  `default:"true" env:"EVENTOPS_SCHEDULE_ENABLE" yaml:"enable"`
+	LoopInterval int64 `default:"30" env:"EVENTOPS_SCHEDULE_LOOP_INTERVAL" yaml:"loopInterval"`
 }
 
 type Process struct {
@@ -63,6 +81,48 @@ type Process struct {
 	LoopLoadEventInterval int `default:"300" env:"EVENTOPS_PROCESS_LOOP_INTERVAL" yaml:"loopLoadEventInterval"`
 }
 
+type Pipeline struct {
+	Gc      Gc      `yaml:"gc"`
+	Log     Log     `yaml:"log"`
+	Timeout Timeout `yaml:"timeout"`
+	Limit   Limit   `yaml:"limit"`
+}
+
+type Limit struct {
+	// 同时运行的流水线的最大数量, 超出的流水线进入排队状态, 0 代表不限制
+	MaxRunningPipelines int `default:"0" env:"EVENTOPS_MAX_RUNNING_PIPELINES" yaml:"maxRunningPipelines"`
+	// 每个用户同时运行的流水线的最大数量, 用户表中设置了 max_running_pipelines 时以用户的为准, 0 代表不限制
+	MaxRunningPipelinesPerUser int `default:"0" env:"EVENTOPS_MAX_RUNNING_PIPELINES_PER_USER" yaml:"maxRunningPipelinesPerUser"`
+	// 每个执行器同时运行的任务的最大数量, 超出的任务等待执行器空闲, 0 代表不限制
+	MaxRunningTasksPerActuator int `default:"0" env:"EVENTOPS_MAX_RUNNING_TASKS_PER_ACTUATOR" yaml:"maxRunningTasksPerActuator"`
+}
+
+type Timeout struct {
+	// 流水线定义和触发器都没有声明 timeout 时流水线的超时时间(秒)
+	Default int64 `default:"86400" env:"EVENTOPS_PIPELINE_DEFAULT_TIMEOUT" yaml:"default"`
+	// 流水线超时时间的最大值(秒), 0 代表不限制
+	Max int64 `default:"604800" env:"EVENTOPS_PIPELINE_MAX_TIMEOUT" yaml:"max"`
+	// 任务没有声明 timeout 时的超时时间(秒)
+	TaskDefault int64 `default:"3600" env:"EVENTOPS_TASK_DEFAULT_TIMEOUT" yaml:"taskDefault"`
+}
+
+type Log struct {
+	// 任务结束后是否归档日志
+	Archive bool `default:"true" env:"EVENTOPS_LOG_ARCHIVE" yaml:"archive"`
+	// 归档日志的最大字节数, 超出的部分只保留最后的日志, 0 使用默认的 4M, 最大为 16M-1
+	ArchiveMaxSize int64 `default:"4194304" env:"EVENTOPS_LOG_ARCHIVE_MAX_SIZE" yaml:"archiveMaxSize"`
+}
+
+type Gc struct {
+	Enable   bool  `default:"true" env:"EVENTOPS_GC_ENABLE" yaml:"enable"`
+	Interval int64 `default:"600" env:"EVENTOPS_GC_INTERVAL" yaml:"interval"`
+	// 流水线结束多少秒后回收执行器上的资源, 0 代表不回收
+	SuccessRetention int64 `default:"3600" env:"EVENTOPS_GC_SUCCESS_RETENTION" yaml:"successRetention"`
+	FailedRetention  int64 `default:"86400" env:"EVENTOPS_GC_FAILED_RETENTION" yaml:"failedRetention"`
+	// 流水线创建多少秒后无论结束状态都进行回收, 0 代表不限制
+	MaxAge int64 `default:"604800" env:"EVENTOPS_GC_MAX_AGE" yaml:"maxAge"`
+}
+
 type Minio struct {
 	Server          string `env:"MINIO_SERVER" yaml:"server"`
 	AccessKeyId     string `env:"MINIO_ACCESS_KEY" yaml:"accessKeyId" yaml:"accessKeyId"`
@@ -115,6 +175,10 @@ func GetMinio() Minio {
 	return conf.Minio
 }
 
+func GetPipeline() Pipeline {
+	return conf.Pipeline
+}
+
 func GetLoginTokenExpiresTime() time.Duration {
 	return time.Second * time.Duration(conf.Uc.LoginTokenExpiresTime)
 }
@@ -134,4 +198,8 @@ func parseConf() {
 		"/api/dialer/connect",
 		"/api/pipeline/callback",
 	}
+	conf.Uc.Auth.WhiteUrlPrefixList = []string{
+		// webhook 使用各自的 secret 校验
+		"/api/event/webhook/",
+	}
 }
@@ -18,6 +18,7 @@ import (
 	"eventops/apistructs"
 	"eventops/pkg/schema/pipeline"
 	"fmt"
+	"io"
 )
 
 type Actuator interface {
@@ -30,6 +31,14 @@ type Actuator interface {
 
 	Remove(context.Context, *Job) error
 	Exist(context.Context, *Job) (bool, error)
+
+	// Logs 获取任务的日志, follow 为 true 时持续输出直到任务结束或者 ctx 结束
+	Logs(ctx context.Context, job *Job, follow bool) (io.ReadCloser, error)
+}
+
+// PipelineRemover 执行器可以选择实现, 回收流水线在执行器上的公共资源, 例如 k8s 的 namespace
+type PipelineRemover interface {
+	RemovePipeline(ctx context.Context, pipelineId string) error
 }
 
 var JobNotFindError = fmt.Errorf("task not find")
@@ -42,6 +51,9 @@ type Job struct {
 	DefinitionTask *pipeline.Task
 	NextCommands   []string
 
+	// 重试的次数, 第一次执行为 0
+	Attempt int
+
 	JobSign string
 	Error   string
 }
@@ -15,24 +15,31 @@ package docker
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"eventops/apistructs"
 	"eventops/internal/core/actuator"
 	actuatorclient "eventops/pkg/schema/actuator"
+	"eventops/pkg/schema/pipeline"
 	"fmt"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/pkg/errors"
 	"github.com/rancher/remotedialer"
 	"golang.org/x/crypto/ssh"
+	"io"
 	"net"
+	"sort"
 	"strings"
 	"time"
 )
 
 type Actuator struct {
-	client *client.Client
+	client     *client.Client
+	definition *actuatorclient.Docker
 }
 
 func NewDockerClient(dockerConfig *actuatorclient.Docker, dialer remotedialer.Dialer) (*Actuator, error) {
@@ -47,12 +54,11 @@ func NewDockerClient(dockerConfig *actuatorclient.Docker, dialer remotedialer.Di
 	}
 
 	if dockerConfig.Ssh != nil {
-		sshConfig := &ssh.ClientConfig{
-			User:            dockerConfig.Ssh.User,
-			Timeout:         time.Second * 5,
-			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-			Auth:            []ssh.AuthMethod{ssh.Password(dockerConfig.Ssh.Password)},
+		sshConfig, err := dockerConfig.Ssh.ClientConfig()
+		if err != nil {
+			return nil, err
 		}
+		sshConfig.Timeout = time.Second * 5
 		conn, err := ssh.Dial("tcp", fmt.Sprintf("%s:%s", dockerConfig.Ssh.Ip, dockerConfig.Ssh.Port), sshConfig)
 		if err != nil {
 			return nil, errors.Wrapf(err, "dail tcp %s err: %v", dockerConfig.Ssh.Ip, err)
@@ -77,16 +83,16 @@ BUILD:
 	}
 
 	return &Actuator{
-		client: cl,
+		client:     cl,
+		definition: dockerConfig,
 	}, nil
 }
 
 func (a *Actuator) Create(ctx context.Context, task *actuator.Job) (*actuator.Job, error) {
-	out, err := a.client.ImagePull(ctx, task.DefinitionTask.Image, types.ImagePullOptions{})
-	if err != nil {
+	options := a.definition.Docker.Merge(task.DefinitionTask.Docker)
+	if err := a.pullImage(ctx, task.DefinitionTask.Image, options.PullPolicy); err != nil {
 		return nil, err
 	}
-	defer out.Close()
 
 	command := fmt.Sprintf("echo 'task %v start'", task.DefinitionTask.Alias)
 	for _, cmd := range task.PreCommands {
@@ -99,10 +105,28 @@ func (a *Actuator) Create(ctx context.Context, task *actuator.Job) (*actuator.Jo
 		command = fmt.Sprintf("%s && %s", command, cmd)
 	}
 
+	resources, err := buildResources(task.DefinitionTask.Resources)
+	if err != nil {
+		return nil, err
+	}
+
+	var env []string
+	for key, value := range options.Env {
+		env = append(env, fmt.Sprintf("%v=%v", key, value))
+	}
+	sort.Strings(env)
+
 	resp, err := a.client.ContainerCreate(ctx, &container.Config{
 		Image: task.DefinitionTask.Image,
 		Cmd:   []string{"sh", "-c", command},
-	}, nil, nil, nil, task.TaskId)
+		Env:   env,
+		User:  options.User,
+	}, &container.HostConfig{
+		Binds:       options.Volumes,
+		NetworkMode: container.NetworkMode(options.NetworkMode),
+		Privileged:  options.Privileged,
+		Resources:   resources,
+	}, nil, nil, containerName(task))
 	if err != nil {
 		return nil, err
 	}
@@ -111,6 +135,105 @@ func (a *Actuator) Create(ctx context.Context, task *actuator.Job) (*actuator.Jo
 	return task, nil
 }
 
+func (a *Actuator) pullImage(ctx context.Context, image string, pullPolicy string) error {
+	switch pullPolicy {
+	case pipeline.NeverPullPolicy:
+		return nil
+	case pipeline.IfNotPresentPullPolicy:
+		_, _, err := a.client.ImageInspectWithRaw(ctx, image)
+		if err == nil {
+			return nil
+		}
+		if !client.IsErrNotFound(err) {
+			return err
+		}
+	}
+
+	registryAuth, err := a.registryAuth(image)
+	if err != nil {
+		return err
+	}
+
+	out, err := a.client.ImagePull(ctx, image, types.ImagePullOptions{RegistryAuth: registryAuth})
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	// 需要读完输出, 否则镜像可能还没有拉取完成
+	_, err = io.Copy(io.Discard, out)
+	return err
+}
+
+// registryAuth 根据镜像的仓库域名找到对应的认证信息, 编码成 docker api 需要的格式
+func (a *Actuator) registryAuth(image string) (string, error) {
+	registry := imageRegistry(image)
+	for _, auth := range a.definition.RegistryAuths {
+		if auth.Registry != registry {
+			continue
+		}
+
+		authJson, err := json.Marshal(types.AuthConfig{
+			Username:      auth.Username,
+			Password:      auth.Password,
+			ServerAddress: auth.Registry,
+		})
+		if err != nil {
+			return "", err
+		}
+		return base64.URLEncoding.EncodeToString(authJson), nil
+	}
+	return "", nil
+}
+
+// imageRegistry 镜像名称第一段包含 . 或 : 或者是 localhost 时才是仓库域名, 否则是 docker hub 的镜像
+func imageRegistry(image string) string {
+	index := strings.Index(image, "/")
+	if index == -1 {
+		return "docker.io"
+	}
+
+	domain := image[:index]
+	if !strings.ContainsAny(domain, ".:") && domain != "localhost" {
+		return "docker.io"
+	}
+	return domain
+}
+
+// buildResources docker 没有 request 的概念, request 的内存作为 MemoryReservation, cpu 按照 1 核 1024 换算成 CPUShares
+func buildResources(resources *pipeline.Resources) (container.Resources, error) {
+	var containerResources container.Resources
+	if resources == nil {
+		return containerResources, nil
+	}
+
+	if resources.Limit != nil {
+		cpu, mem, err := resources.Limit.Quantities()
+		if err != nil {
+			return containerResources, err
+		}
+		containerResources.NanoCPUs = cpu.MilliValue() * 1000000
+		containerResources.Memory = mem.Value()
+	}
+
+	if resources.Request != nil {
+		cpu, mem, err := resources.Request.Quantities()
+		if err != nil {
+			return containerResources, err
+		}
+		containerResources.CPUShares = cpu.MilliValue() * 1024 / 1000
+		containerResources.MemoryReservation = mem.Value()
+	}
+	return containerResources, nil
+}
+
+func containerName(task *actuator.Job) string {
+	if task.Attempt > 0 {
+		return fmt.Sprintf("%v-%v", task.TaskId, task.Attempt)
+	}
+	return task.TaskId
+}
+
 func (a *Actuator) Start(ctx context.Context, task *actuator.Job) error {
 	return a.client.ContainerStart(ctx, task.JobSign, types.ContainerStartOptions{})
 }
@@ -145,6 +268,26 @@ func (a *Actuator) Cancel(ctx context.Context, task *actuator.Job) error {
 	return a.client.ContainerStop(ctx, task.JobSign, nil)
 }
 
+func (a *Actuator) Logs(ctx context.Context, task *actuator.Job, follow bool) (io.ReadCloser, error) {
+	out, err := a.client.ContainerLogs(ctx, task.JobSign, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     follow,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 容器没有开启 tty, 日志中 stdout 和 stderr 是混合在一起的, 需要拆开
+	reader, writer := io.Pipe()
+	go func() {
+		defer out.Close()
+		_, err := stdcopy.StdCopy(writer, writer, out)
+		writer.CloseWithError(err)
+	}()
+	return reader, nil
+}
+
 func (a Actuator) Type() apistructs.TaskType {
 	return apistructs.DockerType
 }
@@ -19,10 +19,15 @@ import (
 	"eventops/apistructs"
 	"eventops/internal/core/actuator"
 	client "eventops/pkg/schema/actuator"
+	"eventops/pkg/schema/pipeline"
 	"fmt"
 	"github.com/rancher/remotedialer"
+	"io"
 	"io/ioutil"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -33,19 +38,16 @@ import (
 )
 
 type Actuator struct {
-	client *kubernetes.Clientset
-	config *restclient.Config
+	client     *kubernetes.Clientset
+	config     *restclient.Config
+	definition *client.Kubernetes
 }
 
-func isPodNotFindError(err error, podName string) bool {
-	if err.Error() == fmt.Sprintf("pods \"%v\" not found", podName) {
-		return true
-	}
-	return false
-}
+// pipelineIdLabel 固定命名空间时用于回收流水线的 job
+const pipelineIdLabel = "eventops/pipeline-id"
 
-func isNsNotFindError(err error, ns string) bool {
-	if err.Error() == fmt.Sprintf("namespaces \"%v\" not found", ns) {
+func isNotFindError(err error, resource string, name string) bool {
+	if err.Error() == fmt.Sprintf("%v \"%v\" not found", resource, name) {
 		return true
 	}
 	return false
@@ -55,33 +57,107 @@ func makeNamespace(namespaceId string) string {
 	return fmt.Sprintf("pipelines-%v", namespaceId)
 }
 
+func (a Actuator) namespace(pipelineId string) string {
+	if a.definition.GetNamespaceStrategy() == client.FixedNamespaceStrategy {
+		return a.definition.Namespace
+	}
+	return makeNamespace(pipelineId)
+}
+
+func makeWorkspaceName(pipelineId string) string {
+	return fmt.Sprintf("workspace-%v", pipelineId)
+}
+
 func (a Actuator) Create(ctx context.Context, task *actuator.Job) (*actuator.Job, error) {
+	if a.definition.GetNamespaceStrategy() == client.PipelineNamespaceStrategy {
+		if err := a.createPipelineNamespace(ctx, task.PipelineId); err != nil {
+			return nil, err
+		}
+	}
+	if a.definition.Workspace != nil {
+		if err := a.createPipelineWorkspace(ctx, task.PipelineId); err != nil {
+			return nil, err
+		}
+	}
+
+	task.JobSign = fmt.Sprintf("%v-%v", task.DefinitionTask.Alias, task.TaskId)
+	if task.Attempt > 0 {
+		task.JobSign = fmt.Sprintf("%v-%v", task.JobSign, task.Attempt)
+	}
+	return task, nil
+}
+
+func (a Actuator) createPipelineNamespace(ctx context.Context, pipelineId string) error {
 	var ns = corev1.Namespace{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Namespace",
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name: makeNamespace(task.PipelineId),
+			Name: makeNamespace(pipelineId),
 		},
 	}
 
-	exist, err := a.client.CoreV1().Namespaces().Get(ctx, makeNamespace(task.PipelineId), metav1.GetOptions{})
+	exist, err := a.client.CoreV1().Namespaces().Get(ctx, makeNamespace(pipelineId), metav1.GetOptions{})
 	if err != nil {
-		if !isNsNotFindError(err, makeNamespace(task.PipelineId)) {
-			return nil, err
+		if !isNotFindError(err, "namespaces", makeNamespace(pipelineId)) {
+			return err
 		}
 		exist = nil
 	}
 	if exist == nil {
 		_, err := a.client.CoreV1().Namespaces().Create(ctx, &ns, metav1.CreateOptions{})
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
+	return nil
+}
 
-	task.JobSign = fmt.Sprintf("%v-%v", task.DefinitionTask.Alias, task.TaskId)
-	return task, nil
+func (a Actuator) createPipelineWorkspace(ctx context.Context, pipelineId string) error {
+	_, err := a.client.CoreV1().PersistentVolumeClaims(a.namespace(pipelineId)).Get(ctx, makeWorkspaceName(pipelineId), metav1.GetOptions{})
+	if err == nil {
+		return nil
+	}
+	if !isNotFindError(err, "persistentvolumeclaims", makeWorkspaceName(pipelineId)) {
+		return err
+	}
+
+	size, err := resource.ParseQuantity(a.definition.Workspace.Size)
+	if err != nil {
+		return err
+	}
+
+	var pvc = corev1.PersistentVolumeClaim{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PersistentVolumeClaim",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: makeWorkspaceName(pipelineId),
+			Labels: map[string]string{
+				pipelineIdLabel: pipelineId,
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.PersistentVolumeAccessMode(a.definition.Workspace.AccessMode)},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: size,
+				},
+			},
+		},
+	}
+	// 没有声明 storageClassName 时使用集群默认的 storageClass
+	if a.definition.Workspace.StorageClassName != "" {
+		pvc.Spec.StorageClassName = &a.definition.Workspace.StorageClassName
+	}
+
+	_, err = a.client.CoreV1().PersistentVolumeClaims(a.namespace(pipelineId)).Create(ctx, &pvc, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
 }
 
 func (a Actuator) Start(ctx context.Context, task *actuator.Job) error {
@@ -104,39 +180,187 @@ func (a Actuator) Start(ctx context.Context, task *actuator.Job) error {
 		command = fmt.Sprintf("%s && %s", command, cmd)
 	}
 
-	pod := corev1.Pod{
+	resources, err := buildResourceRequirements(task.DefinitionTask.Resources)
+	if err != nil {
+		return err
+	}
+
+	var labels = map[string]string{}
+	for key, value := range a.definition.Labels {
+		labels[key] = value
+	}
+	labels[pipelineIdLabel] = task.PipelineId
+
+	var imagePullSecrets []corev1.LocalObjectReference
+	for _, secret := range a.definition.ImagePullSecrets {
+		imagePullSecrets = append(imagePullSecrets, corev1.LocalObjectReference{Name: secret})
+	}
+
+	var tolerations []corev1.Toleration
+	for _, toleration := range a.definition.Tolerations {
+		tolerations = append(tolerations, corev1.Toleration{
+			Key:               toleration.Key,
+			Operator:          corev1.TolerationOperator(toleration.Operator),
+			Value:             toleration.Value,
+			Effect:            corev1.TaintEffect(toleration.Effect),
+			TolerationSeconds: toleration.TolerationSeconds,
+		})
+	}
+
+	var securityContext *corev1.PodSecurityContext
+	if a.definition.SecurityContext != nil {
+		securityContext = &corev1.PodSecurityContext{
+			RunAsUser:    a.definition.SecurityContext.RunAsUser,
+			RunAsGroup:   a.definition.SecurityContext.RunAsGroup,
+			RunAsNonRoot: a.definition.SecurityContext.RunAsNonRoot,
+			FSGroup:      a.definition.SecurityContext.FsGroup,
+		}
+	}
+
+	// 失败后由流水线的 retry 重新创建 job, job 自身不重试
+	job := batchv1.Job{
 		TypeMeta: metav1.TypeMeta{
-			Kind:       "pod",
-			APIVersion: "v1",
+			Kind:       "Job",
+			APIVersion: "batch/v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name: task.JobSign,
+			Name:        task.JobSign,
+			Labels:      labels,
+			Annotations: a.definition.Annotations,
 		},
-		Spec: corev1.PodSpec{
-			RestartPolicy:         corev1.RestartPolicyNever,
-			ShareProcessNamespace: &[]bool{true}[0],
-			Containers: []corev1.Container{
-				{
-					Name:    task.DefinitionTask.Alias,
-					Image:   task.DefinitionTask.Image,
-					Command: []string{"sh"},
-					Args:    []string{"-c", command},
-					Stdin:   true,
-					TTY:     true,
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &[]int32{0}[0],
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      labels,
+					Annotations: a.definition.Annotations,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy:         corev1.RestartPolicyNever,
+					ShareProcessNamespace: &[]bool{true}[0],
+					NodeSelector:          a.definition.NodeSelector,
+					Tolerations:           tolerations,
+					ServiceAccountName:    a.definition.ServiceAccountName,
+					ImagePullSecrets:      imagePullSecrets,
+					SecurityContext:       securityContext,
+					Containers: []corev1.Container{
+						{
+							Name:      task.DefinitionTask.Alias,
+							Image:     task.DefinitionTask.Image,
+							Command:   []string{"sh"},
+							Args:      []string{"-c", command},
+							Stdin:     true,
+							TTY:       true,
+							Resources: resources,
+						},
+					},
 				},
 			},
 		},
 	}
+	if a.definition.Workspace != nil {
+		podSpec := &job.Spec.Template.Spec
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: "workspace",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: makeWorkspaceName(task.PipelineId),
+				},
+			},
+		})
+		podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      "workspace",
+			MountPath: client.WorkspaceMountPath,
+		})
+	}
+	if task.DefinitionTask.Timeout > 0 {
+		job.Spec.ActiveDeadlineSeconds = &task.DefinitionTask.Timeout
+	}
+	if a.definition.TtlSecondsAfterFinished > 0 {
+		job.Spec.TTLSecondsAfterFinished = &a.definition.TtlSecondsAfterFinished
+	}
 
-	_, err := a.client.CoreV1().Pods(makeNamespace(task.PipelineId)).Create(ctx, &pod, metav1.CreateOptions{})
+	_, err = a.client.BatchV1().Jobs(a.namespace(task.PipelineId)).Create(ctx, &job, metav1.CreateOptions{})
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+func buildResourceRequirements(resources *pipeline.Resources) (corev1.ResourceRequirements, error) {
+	var requirements corev1.ResourceRequirements
+	if resources == nil {
+		return requirements, nil
+	}
+
+	if resources.Limit != nil {
+		cpu, mem, err := resources.Limit.Quantities()
+		if err != nil {
+			return requirements, err
+		}
+		requirements.Limits = corev1.ResourceList{
+			corev1.ResourceCPU:    cpu,
+			corev1.ResourceMemory: mem,
+		}
+	}
+
+	if resources.Request != nil {
+		cpu, mem, err := resources.Request.Quantities()
+		if err != nil {
+			return requirements, err
+		}
+		requirements.Requests = corev1.ResourceList{
+			corev1.ResourceCPU:    cpu,
+			corev1.ResourceMemory: mem,
+		}
+	}
+	return requirements, nil
+}
+
 func (a Actuator) Remove(ctx context.Context, task *actuator.Job) error {
-	return a.client.CoreV1().Pods(makeNamespace(task.PipelineId)).Delete(ctx, task.JobSign, metav1.DeleteOptions{})
+	propagation := metav1.DeletePropagationBackground
+	return a.client.BatchV1().Jobs(a.namespace(task.PipelineId)).Delete(ctx, task.JobSign, metav1.DeleteOptions{PropagationPolicy: &propagation})
+}
+
+func (a Actuator) RemovePipeline(ctx context.Context, pipelineId string) error {
+	if a.definition.GetNamespaceStrategy() == client.FixedNamespaceStrategy {
+		propagation := metav1.DeletePropagationBackground
+		err := a.client.BatchV1().Jobs(a.namespace(pipelineId)).DeleteCollection(ctx, metav1.DeleteOptions{PropagationPolicy: &propagation}, metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("%v=%v", pipelineIdLabel, pipelineId),
+		})
+		if err != nil {
+			return err
+		}
+
+		// pvc 会等使用它的 pod 删除后才被真正删除
+		if a.definition.Workspace != nil {
+			err = a.client.CoreV1().PersistentVolumeClaims(a.namespace(pipelineId)).Delete(ctx, makeWorkspaceName(pipelineId), metav1.DeleteOptions{})
+			if err != nil && !isNotFindError(err, "persistentvolumeclaims", makeWorkspaceName(pipelineId)) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := a.client.CoreV1().Namespaces().Delete(ctx, makeNamespace(pipelineId), metav1.DeleteOptions{})
+	if err != nil && !isNotFindError(err, "namespaces", makeNamespace(pipelineId)) {
+		return err
+	}
+	return nil
+}
+
+// getJobPod job 的 backoffLimit 为 0, 只会有一个 pod
+func (a Actuator) getJobPod(ctx context.Context, task *actuator.Job) (*corev1.Pod, error) {
+	pods, err := a.client.CoreV1().Pods(a.namespace(task.PipelineId)).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("job-name=%v", task.JobSign),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(pods.Items) == 0 {
+		return nil, actuator.JobNotFindError
+	}
+	return &pods.Items[0], nil
 }
 
 func (a Actuator) Cancel(ctx context.Context, task *actuator.Job) error {
@@ -150,11 +374,20 @@ func (a Actuator) Cancel(ctx context.Context, task *actuator.Job) error {
 	if status.IsDoneStatus() {
 		return nil
 	}
+
+	pod, err := a.getJobPod(ctx, task)
+	if err != nil {
+		if err == actuator.JobNotFindError {
+			return nil
+		}
+		return err
+	}
+
 	// 构造执行命令请求
 	req := a.client.CoreV1().RESTClient().Post().
 		Resource("pods").
-		Name(task.JobSign).
-		Namespace(makeNamespace(task.PipelineId)).
+		Name(pod.Name).
+		Namespace(a.namespace(task.PipelineId)).
 		SubResource("exec").
 		VersionedParams(&corev1.PodExecOptions{
 			Command: []string{"sh", "-c", "kill -15 `ps | awk '{print $1}' | awk 'NR == 3'`"},
@@ -187,52 +420,69 @@ func (a Actuator) Cancel(ctx context.Context, task *actuator.Job) error {
 }
 
 func (a Actuator) Exist(ctx context.Context, task *actuator.Job) (bool, error) {
-	pod, err := a.client.CoreV1().Pods(makeNamespace(task.PipelineId)).Get(ctx, task.JobSign, metav1.GetOptions{})
+	job, err := a.client.BatchV1().Jobs(a.namespace(task.PipelineId)).Get(ctx, task.JobSign, metav1.GetOptions{})
 	if err != nil {
-		if !isPodNotFindError(err, task.JobSign) {
+		if !isNotFindError(err, "jobs.batch", task.JobSign) {
 			return false, err
 		}
 		return false, nil
 	}
-	if pod != nil {
+	if job != nil {
 		return true, nil
 	}
 	return false, nil
 }
 
+func (a Actuator) Logs(ctx context.Context, task *actuator.Job, follow bool) (io.ReadCloser, error) {
+	pod, err := a.getJobPod(ctx, task)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.client.CoreV1().Pods(a.namespace(task.PipelineId)).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Follow: follow,
+	}).Stream(ctx)
+}
+
 func (a Actuator) Type() apistructs.TaskType {
 	return apistructs.K8sType
 }
 
 func (a Actuator) Status(ctx context.Context, task *actuator.Job) (apistructs.TaskStatus, error) {
-	pod, err := a.client.CoreV1().Pods(makeNamespace(task.PipelineId)).Get(ctx, task.JobSign, metav1.GetOptions{})
+	job, err := a.client.BatchV1().Jobs(a.namespace(task.PipelineId)).Get(ctx, task.JobSign, metav1.GetOptions{})
 	if err != nil {
-		if !isPodNotFindError(err, task.JobSign) {
+		if !isNotFindError(err, "jobs.batch", task.JobSign) {
 			return "", err
 		}
 		return "", actuator.JobNotFindError
 	}
-	if pod == nil {
+	if job == nil {
 		return "", actuator.JobNotFindError
 	}
 
-	var result apistructs.TaskStatus
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
 
-	switch pod.Status.Phase {
-	case corev1.PodPending:
-		result = apistructs.RunningTaskStatus
-	case corev1.PodRunning:
-		result = apistructs.RunningTaskStatus
-	case corev1.PodSucceeded:
-		result = apistructs.SuccessTaskStatus
-	case corev1.PodFailed:
-		result = apistructs.FailedTaskStatus
-		task.Error = getContainerExitCode(pod)
-	default:
-		result = apistructs.UnKnowTaskStatus
-		task.Error = getContainerExitCode(pod)
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return apistructs.SuccessTaskStatus, nil
+		case batchv1.JobFailed:
+			// 超过 activeDeadlineSeconds 的 job 会被 k8s 停止
+			if condition.Reason == "DeadlineExceeded" {
+				task.Error = condition.Message
+				return apistructs.TimeoutTaskStatus, nil
+			}
+
+			task.Error = condition.Message
+			if pod, err := a.getJobPod(ctx, task); err == nil {
+				task.Error = getContainerExitCode(pod)
+			}
+			return apistructs.FailedTaskStatus, nil
+		}
 	}
-	return result, nil
+	return apistructs.RunningTaskStatus, nil
 }
 
 func getContainerExitCode(pod *corev1.Pod) string {
@@ -283,7 +533,8 @@ func NewKubernetesClient(k8sConfig *client.Kubernetes, dialer remotedialer.Diale
 	}
 
 	return &Actuator{
-		client: clientset,
-		config: config,
+		client:     clientset,
+		config:     config,
+		definition: k8sConfig,
 	}, nil
 }
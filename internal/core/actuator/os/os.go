@@ -22,8 +22,10 @@ import (
 	"github.com/melbahja/goph"
 	"github.com/rancher/remotedialer"
 	"golang.org/x/crypto/ssh"
+	"io"
 	"net"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -82,6 +84,8 @@ func (a Actuator) createRunShell(ctx context.Context, task *actuator.Job) error
 
 	var command = "#!/bin/bash\n"
 	command += fmt.Sprintf("echo $$ > nohup.pid \n")
+	// 和 docker k8s 的 && 一致, 命令失败后不再执行后面的命令, 缓存和出参只在用户命令成功后保存
+	command += "set -e\n"
 
 	for _, cmd := range task.PreCommands {
 		command += fmt.Sprintf("%v\n", cmd)
@@ -128,6 +132,11 @@ func (a Actuator) Remove(ctx context.Context, task *actuator.Job) error {
 	return err
 }
 
+func (a Actuator) RemovePipeline(ctx context.Context, pipelineId string) error {
+	_, err := a.client.RunContext(ctx, fmt.Sprintf("rm -rf pipelines/%v", pipelineId))
+	return err
+}
+
 func (a Actuator) Cancel(ctx context.Context, task *actuator.Job) error {
 	status, err := a.Status(ctx, task)
 	if err != nil {
@@ -206,6 +215,54 @@ fi;
 	}
 }
 
+func (a Actuator) Logs(ctx context.Context, task *actuator.Job, follow bool) (io.ReadCloser, error) {
+	command := fmt.Sprintf("cd %v && cat nohup.log", workDir(task.PipelineId, task.TaskId))
+	if follow && task.JobSign != "" {
+		// run.sh 进程结束后 tail 会自动退出
+		command = fmt.Sprintf("cd %v && tail -n +1 -F --pid=%v nohup.log", workDir(task.PipelineId, task.TaskId), task.JobSign)
+	}
+
+	session, err := a.client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		session.Close()
+		return nil, err
+	}
+	if err := session.Start(command); err != nil {
+		session.Close()
+		return nil, err
+	}
+
+	reader := &sessionReader{Reader: stdout, session: session, done: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			reader.Close()
+		case <-reader.done:
+		}
+	}()
+	return reader, nil
+}
+
+type sessionReader struct {
+	io.Reader
+	session *ssh.Session
+	once    sync.Once
+	done    chan struct{}
+}
+
+func (r *sessionReader) Close() error {
+	var err error
+	r.once.Do(func() {
+		close(r.done)
+		err = r.session.Close()
+	})
+	return err
+}
+
 func (a Actuator) Type() apistructs.TaskType {
 	return apistructs.OsType
 }
@@ -226,12 +283,14 @@ func NewOsClient(osConfig *client.Os, dialer remotedialer.Dialer) (*Actuator, er
 		}
 	}
 
-	sshConn, a, b, err := ssh.NewClientConn(conn, fmt.Sprintf("%s:%s", osConfig.Ip, osConfig.Port), &ssh.ClientConfig{
-		User:            osConfig.User,
-		Auth:            []ssh.AuthMethod{ssh.Password(osConfig.Password)},
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-	})
+	sshConfig, err := osConfig.ClientConfig()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	sshConn, a, b, err := ssh.NewClientConn(conn, fmt.Sprintf("%s:%s", osConfig.Ip, osConfig.Port), sshConfig)
 	if err != nil {
+		conn.Close()
 		return nil, err
 	}
 
@@ -16,8 +16,10 @@ package eventclient
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"eventops/apistructs"
 	"fmt"
+	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
 	"time"
 )
@@ -31,6 +33,9 @@ type Event struct {
 	Status        apistructs.EventStatus `json:"status"`
 	StatusMessage string                 `json:"status_Message"`
 
+	IdempotencyKey string `json:"idempotency_key"`
+	ContentHash    string `json:"content_hash"`
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt
@@ -190,6 +195,50 @@ func (client *Client) CreateEvent(tx *gorm.DB, t *Event) (*Event, error) {
 	return t, nil
 }
 
+type DuplicateEventQuery struct {
+	Name           string
+	Creater        string
+	IdempotencyKey string
+	ContentHash    string
+	// 只查找这个时间之后创建的事件, 为空的时候不限制
+	CreatedAfter *time.Time
+}
+
+// IsConcurrentCreateError 并发保存相同的事件时唯一键冲突(1062)或者死锁(1213)
+func IsConcurrentCreateError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && (mysqlErr.Number == 1062 || mysqlErr.Number == 1213)
+}
+
+// GetDuplicateEvent 优先使用 IdempotencyKey 查找, 没有 IdempotencyKey 的时候使用 ContentHash 查找
+func (client *Client) GetDuplicateEvent(tx *gorm.DB, query DuplicateEventQuery) (*Event, bool, error) {
+	if tx == nil {
+		tx = client.client
+	}
+
+	tx = tx.Where("name = ? and creater = ?", query.Name, query.Creater)
+	if query.IdempotencyKey != "" {
+		tx = tx.Where("idempotency_key = ?", query.IdempotencyKey)
+	} else if query.ContentHash != "" {
+		tx = tx.Where("content_hash = ?", query.ContentHash)
+	} else {
+		return nil, false, nil
+	}
+	if query.CreatedAfter != nil {
+		tx = tx.Where("created_at > ?", query.CreatedAfter)
+	}
+
+	var event Event
+	err := tx.Order("id asc").First(&event).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return &event, true, nil
+}
+
 func (client *Client) GetEvent(tx *gorm.DB, name string, version string, creater string) (*Event, error) {
 	if tx == nil {
 		tx = client.client
@@ -38,6 +38,8 @@ type Pipeline struct {
 	CostTimeSec         uint64                    `json:"cost_time_sec"`
 	TimeBegin           *time.Time                `json:"time_begin"`
 	TimeEnd             *time.Time                `json:"time_end"`
+	ConcurrencyGroup    string                    `json:"concurrency_group"`
+	Priority            int                       `json:"priority"`
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -57,6 +59,8 @@ func (p Pipeline) ToApiStruct() apistructs.Pipeline {
 		CostTimeSec:         p.CostTimeSec,
 		TimeBegin:           p.TimeBegin,
 		TimeEnd:             p.TimeEnd,
+		ConcurrencyGroup:    p.ConcurrencyGroup,
+		Priority:            p.Priority,
 
 		CreatedAt: p.CreatedAt,
 		UpdatedAt: p.UpdatedAt,
@@ -102,11 +106,25 @@ func (p PipelineExtra) ToApiStruct() apistructs.PipelineExtra {
 
 type PipelineExtraInfo struct {
 	StopReason string `json:"stop_reason,omitempty"`
+	RerunFrom  uint64 `json:"rerun_from,omitempty"`
+
+	FinallyStatus apistructs.TaskStatus `json:"finally_status,omitempty"`
+	FinallyError  string                `json:"finally_error,omitempty"`
+	Warnings      []string              `json:"warnings,omitempty"`
+
+	// PausedAt 本次暂停的开始时间, PausedSec 累计暂停的秒数, 暂停的时间不计入流水线超时
+	PausedAt  *time.Time `json:"paused_at,omitempty"`
+	PausedSec uint64     `json:"paused_sec,omitempty"`
 }
 
 func (p PipelineExtraInfo) ToApiStruct() apistructs.PipelineExtraInfo {
 	extra := apistructs.PipelineExtraInfo{
 		StopReason: p.StopReason,
+		RerunFrom:  p.RerunFrom,
+
+		FinallyStatus: p.FinallyStatus,
+		FinallyError:  p.FinallyError,
+		Warnings:      p.Warnings,
 	}
 	return extra
 }
@@ -239,6 +257,15 @@ type ListPipelineQuery struct {
 	Top uint64
 
 	Statuses []apistructs.PipelineStatus
+
+	ConcurrencyGroup string
+	// 默认按照 id 倒序, 排队的流水线需要按照 id 正序取出
+	OrderByIdAsc bool
+
+	TimeEndBefore    *time.Time
+	CreatedBefore    *time.Time
+	HasUncleanedTask bool
+	ExcludeIds       []uint64
 }
 
 func (client *Client) ListPipeline(tx *gorm.DB, query ListPipelineQuery) ([]Pipeline, error) {
@@ -258,6 +285,10 @@ func (client *Client) ListPipeline(tx *gorm.DB, query ListPipelineQuery) ([]Pipe
 		tx = tx.Where("event_trigger_id = ?", query.EventTriggerId)
 	}
 
+	if query.ConcurrencyGroup != "" {
+		tx = tx.Where("concurrency_group = ?", query.ConcurrencyGroup)
+	}
+
 	if query.TriggerDefinitionId > 0 {
 		tx = tx.Where("trigger_definition_id = ?", query.TriggerDefinitionId)
 	}
@@ -270,10 +301,30 @@ func (client *Client) ListPipeline(tx *gorm.DB, query ListPipelineQuery) ([]Pipe
 		tx = tx.Where("definition_name = ? && definition_version = ? && definition_creater = ?",
 			query.PipelineDefinitionName, query.PipelineDefinitionVersion, query.PipelineDefinitionCreater)
 	}
+	if query.TimeEndBefore != nil {
+		tx = tx.Where("time_end < ?", query.TimeEndBefore)
+	}
+
+	if query.CreatedBefore != nil {
+		tx = tx.Where("created_at < ?", query.CreatedBefore)
+	}
+
+	if query.HasUncleanedTask {
+		tx = tx.Where("id in (select pipeline_id from pipeline_tasks where cleaned = ?)", false)
+	}
+
+	if len(query.ExcludeIds) > 0 {
+		tx = tx.Where("id not in (?)", query.ExcludeIds)
+	}
+
 	if query.Top > 0 {
 		tx = tx.Limit(int(query.Top))
 	}
-	tx = tx.Order("id desc")
+	if query.OrderByIdAsc {
+		tx = tx.Order("id asc")
+	} else {
+		tx = tx.Order("id desc")
+	}
 
 	var list []Pipeline
 	err := tx.Find(&list).Error
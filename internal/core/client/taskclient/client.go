@@ -36,6 +36,7 @@ type Task struct {
 	TimeBegin    *time.Time            `json:"time_begin"`
 	TimeEnd      *time.Time            `json:"time_end"`
 	Creater      string                `json:"creater"`
+	Cleaned      bool                  `json:"cleaned"`
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -53,6 +54,9 @@ func (t Task) ToApiStruct() apistructs.Task {
 		Extra: &apistructs.TaskExtra{
 			Error:     t.Extra.Error,
 			ChooseTag: t.Extra.ChooseTag,
+			Attempts:  t.Extra.Attempts,
+			Approval:  t.Extra.Approval,
+			Matrix:    t.Extra.Matrix,
 			Inputs: func() apistructs.Inputs {
 				if t.Extra.Inputs == nil {
 					return nil
@@ -79,6 +83,7 @@ func (t Task) ToApiStruct() apistructs.Task {
 		TimeBegin:   t.TimeBegin,
 		TimeEnd:     t.TimeEnd,
 		Creater:     t.Creater,
+		Cleaned:     t.Cleaned,
 
 		CreatedAt: t.CreatedAt,
 		UpdatedAt: t.UpdatedAt,
@@ -90,11 +95,17 @@ func (t Task) TableName() string {
 }
 
 type TaskExtra struct {
-	Error     string   `json:"error,omitempty"`
-	ChooseTag string   `json:"chooseTag,omitempty"`
-	Inputs    Inputs   `json:"inputs,omitempty"`
-	Contexts  Contexts `json:"contexts,omitempty"`
-	Auth      string   `json:"auth,omitempty"`
+	Error     string                   `json:"error,omitempty"`
+	ChooseTag string                   `json:"chooseTag,omitempty"`
+	Inputs    Inputs                   `json:"inputs,omitempty"`
+	Contexts  Contexts                 `json:"contexts,omitempty"`
+	Auth      string                   `json:"auth,omitempty"`
+	Attempts  []apistructs.TaskAttempt `json:"attempts,omitempty"`
+	Approval  *apistructs.TaskApproval `json:"approval,omitempty"`
+	Matrix    map[string]string        `json:"matrix,omitempty"`
+
+	// AttemptTimeBegin 本次执行的 job 创建的时间, 超时时间从这里开始计算, 不包含重试等待和排队的时间
+	AttemptTimeBegin *time.Time `json:"attemptTimeBegin,omitempty"`
 }
 
 type Inputs apistructs.Inputs
@@ -203,3 +214,14 @@ func (client *Client) ListTasks(tx *gorm.DB, pipelineId uint64, user string) ([]
 	}
 	return task, nil
 }
+
+func (client *Client) UpdateTaskCleaned(tx *gorm.DB, ids []uint64) error {
+	if tx == nil {
+		tx = client.client
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return tx.Model(&Task{}).Where("id in (?)", ids).Update("cleaned", true).Error
+}
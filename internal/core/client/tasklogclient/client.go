@@ -0,0 +1,79 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tasklogclient
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+type Client struct {
+	client *gorm.DB
+}
+
+func NewTaskLogClient(client *gorm.DB) *Client {
+	return &Client{client: client}
+}
+
+// TaskLog 任务结束后归档的日志, 每次重试都会归档一条
+type TaskLog struct {
+	Id         uint64 `json:"id"`
+	PipelineId uint64 `json:"pipeline_id"`
+	TaskId     uint64 `json:"task_id"`
+	Attempt    int    `json:"attempt"`
+	Content    string `json:"content"`
+	Truncated  bool   `json:"truncated"`
+	Creater    string `json:"creater"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (l TaskLog) TableName() string {
+	return "pipeline_task_logs"
+}
+
+func (client *Client) CreateTaskLog(tx *gorm.DB, l *TaskLog) (*TaskLog, error) {
+	if tx == nil {
+		tx = client.client
+	}
+
+	err := tx.Create(l).Error
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// GetTaskLog attempt 小于 0 时返回最后一次执行的日志
+func (client *Client) GetTaskLog(tx *gorm.DB, taskId uint64, attempt int, creater string) (*TaskLog, bool, error) {
+	if tx == nil {
+		tx = client.client
+	}
+
+	tx = tx.Where("task_id = ? and creater = ?", taskId, creater)
+	if attempt >= 0 {
+		tx = tx.Where("attempt = ?", attempt)
+	}
+
+	var result TaskLog
+	err := tx.Order("attempt desc").First(&result).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return &result, true, nil
+}
@@ -31,6 +31,9 @@ type EventTriggerDefinition struct {
 	EventVersion string `json:"event_version"`
 	EventCreater string `json:"event_creater"`
 
+	Schedule         string     `json:"schedule"`
+	ScheduleLastTime *time.Time `json:"schedule_last_time"`
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	DeletedAt gorm.DeletedAt
@@ -97,13 +100,33 @@ func (client *Client) UpdateEventTriggerDefinition(tx *gorm.DB, t *EventTriggerD
 	if tx == nil {
 		tx = client.client
 	}
-	err := tx.Model(&EventTriggerDefinition{}).Select("*").Where("id = ?", t.Id).Updates(t).Error
+	// schedule_last_time 只能通过 UpdateScheduleLastTime 修改, 避免覆盖定时任务刚刚记录的触发时间
+	err := tx.Model(&EventTriggerDefinition{}).Select("*").Omit("schedule_last_time").Where("id = ?", t.Id).Updates(t).Error
 	if err != nil {
 		return nil, err
 	}
 	return t, nil
 }
 
+// UpdateScheduleLastTime 只有 schedule_last_time 还是 oldTime 的时候才会更新, 返回是否更新成功
+func (client *Client) UpdateScheduleLastTime(tx *gorm.DB, id uint64, oldTime *time.Time, newTime time.Time) (bool, error) {
+	if tx == nil {
+		tx = client.client
+	}
+
+	tx = tx.Model(&EventTriggerDefinition{}).Where("id = ?", id)
+	if oldTime == nil {
+		tx = tx.Where("schedule_last_time is null")
+	} else {
+		tx = tx.Where("schedule_last_time = ?", oldTime)
+	}
+	result := tx.Update("schedule_last_time", newTime)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
 func (client *Client) CreateEventTriggerDefinition(tx *gorm.DB, t *EventTriggerDefinition) (*EventTriggerDefinition, error) {
 	if tx == nil {
 		tx = client.client
@@ -129,6 +152,7 @@ type ListEventTriggerDefinitionQuery struct {
 	EventVersion string
 	EventCreater string
 	TriggerName  string
+	HasSchedule  bool
 }
 
 func (client *Client) ListEventTriggerDefinition(tx *gorm.DB, query ListEventTriggerDefinitionQuery) ([]EventTriggerDefinition, error) {
@@ -151,6 +175,9 @@ func (client *Client) ListEventTriggerDefinition(tx *gorm.DB, query ListEventTri
 	if query.EventCreater != "" {
 		tx = tx.Where("event_creater = ?", query.EventCreater)
 	}
+	if query.HasSchedule {
+		tx = tx.Where("schedule != ''")
+	}
 
 	var list []EventTriggerDefinition
 	err := tx.Find(&list).Error
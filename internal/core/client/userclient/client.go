@@ -19,14 +19,18 @@ import (
 )
 
 type User struct {
-	Id        uint
-	Name      string
-	Email     string
-	Password  string
-	Salt      string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	DeletedAt gorm.DeletedAt
+	Id       uint
+	Name     string
+	Email    string
+	Password string
+	Salt     string
+	// 同时运行的流水线的最大数量, 0 代表使用服务端的配置
+	MaxRunningPipelines int
+	// 排队时按照运行中的流水线数量和权重的比值公平调度, 小于 1 时按照 1 计算
+	ScheduleWeight int
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt
 }
 
 type Client struct {
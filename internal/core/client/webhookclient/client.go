@@ -0,0 +1,122 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webhookclient
+
+import (
+	"errors"
+	"eventops/apistructs"
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"time"
+)
+
+type Client struct {
+	client *gorm.DB
+}
+
+func NewWebhookClient(client *gorm.DB) *Client {
+	return &Client{client: client}
+}
+
+type Webhook struct {
+	Id       uint64 `json:"id"`
+	Name     string `json:"name"`
+	Creater  string `json:"creater"`
+	Provider string `json:"provider"`
+	Content  string `json:"content"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+// ToApiStructs content 中包含 secret, 不返回给用户
+func (w Webhook) ToApiStructs() apistructs.Webhook {
+	return apistructs.Webhook{
+		Name:      w.Name,
+		Creater:   w.Creater,
+		Provider:  w.Provider,
+		CreatedAt: w.CreatedAt,
+		UpdatedAt: w.UpdatedAt,
+	}
+}
+
+// GetWebhook webhook 的回调地址中没有创建人, 所以名称是全局唯一的
+func (client *Client) GetWebhook(tx *gorm.DB, name string) (*Webhook, bool, error) {
+	if tx == nil {
+		tx = client.client
+	}
+
+	var result Webhook
+	err := tx.Where("name = ?", name).First(&result).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return &result, true, nil
+}
+
+// NameConflictError 名称有唯一索引, 并发创建同名的 webhook 时只有一个能成功
+var NameConflictError = errors.New("webhook name already exists")
+
+func (client *Client) CreateWebhook(tx *gorm.DB, w *Webhook) (*Webhook, error) {
+	if tx == nil {
+		tx = client.client
+	}
+
+	err := tx.Create(w).Error
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+			return nil, NameConflictError
+		}
+		return nil, err
+	}
+	return w, nil
+}
+
+func (client *Client) UpdateWebhook(tx *gorm.DB, w *Webhook) (*Webhook, error) {
+	if tx == nil {
+		tx = client.client
+	}
+
+	err := tx.Model(&Webhook{}).Select("*").Where("id = ?", w.Id).Updates(w).Error
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (client *Client) DeleteWebhook(tx *gorm.DB, name, creater string) error {
+	if tx == nil {
+		tx = client.client
+	}
+	// 名称有唯一索引, 需要真正删除才能重新创建同名的 webhook
+	return tx.Unscoped().Model(&Webhook{}).Where("name = ? and creater = ?", name, creater).Delete(&Webhook{}).Error
+}
+
+func (client *Client) ListWebhook(tx *gorm.DB, creater string) ([]Webhook, error) {
+	if tx == nil {
+		tx = client.client
+	}
+
+	var list []Webhook
+	err := tx.Where("creater = ?", creater).Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
@@ -26,7 +26,6 @@ import (
 	"fmt"
 	"github.com/bluele/gcache"
 	"github.com/sirupsen/logrus"
-	"github.com/tidwall/gjson"
 	"gopkg.in/yaml.v3"
 	"gorm.io/gorm"
 	"time"
@@ -43,10 +42,12 @@ type Process struct {
 
 	Cache       gcache.Cache
 	flowManager *flowmanager.FlowManager
+
+	scheduleStore scheduleStore
 }
 
 func NewProcess(dbClient *gorm.DB, ctx context.Context, flowManager *flowmanager.FlowManager) *Process {
-	return &Process{
+	process := &Process{
 		Buffer:                  make(chan eventclient.Event, conf.GetEvent().Process.BufferSize),
 		WorkNum:                 conf.GetEvent().Process.WorkNum,
 		eventDbClient:           eventclient.NewEventClient(dbClient),
@@ -56,6 +57,9 @@ func NewProcess(dbClient *gorm.DB, ctx context.Context, flowManager *flowmanager
 		Cache:                   gcache.New(conf.GetEvent().Process.TriggerCacheSize).LRU().Build(),
 		flowManager:             flowManager,
 	}
+	process.scheduleStore = process
+	flowManager.SetEventHandler(process.AddToProcess)
+	return process
 }
 
 func (p *Process) MakeCacheKey(eventName string, eventVersion string, eventCreater string) string {
@@ -99,7 +103,7 @@ func (p *Process) LoopLoadProcessingEventToPass() {
 			if e.Status == apistructs.EventProcessingStatus && time.Now().Unix()-e.UpdatedAt.Unix() > conf.GetEvent().Process.ProcessingOverTime {
 				err := p.eventDbClient.UpdateEventStatus(nil, e.Id, e.Status, apistructs.EventCreatedStatus, "")
 				if err != nil {
-					logrus.Error("[event process] update event: %s status to %s status error: %v", e.Status, apistructs.EventCreatedStatus, err)
+					logrus.Errorf("[event process] update event: %s status to %s status error: %v", e.Status, apistructs.EventCreatedStatus, err)
 					continue
 				}
 				e.Status = apistructs.EventCreatedStatus
@@ -163,7 +167,7 @@ func (p *Process) ProcessEvent() {
 					}
 					err := p.process(bufferEvent)
 					if err != nil {
-						logrus.Error("process event name %v version %v creater %v error: %v", bufferEvent.Name, bufferEvent.Version, bufferEvent.Creater, err)
+						logrus.Errorf("process event name %v version %v creater %v error: %v", bufferEvent.Name, bufferEvent.Version, bufferEvent.Creater, err)
 					}
 				}
 			}
@@ -193,7 +197,7 @@ func (p *Process) getAndSetTriggerFromCaches(dbEvent *eventclient.Event) ([]Trig
 			err := yaml.Unmarshal([]byte(dbTrigger.Content), &eventTrigger)
 			if err != nil {
 				// todo 有可能导致这个 trigger 永远无法使用，待观察和测试
-				logrus.Error("failed to Unmarshal trigger content: %v", dbTrigger.Content)
+				logrus.Errorf("failed to Unmarshal trigger content: %v", dbTrigger.Content)
 				continue
 			}
 
@@ -254,12 +258,11 @@ func (p *Process) process(processEvent eventclient.Event) error {
 			continue
 		}
 
-		for index := range trigger.Pipelines {
-			trigger.Pipelines[index].Filters = append(trigger.Pipelines[index].Filters, trigger.Filters...)
-		}
-
 		for _, pipeline := range trigger.Pipelines {
-			pass := checkPass(pipeline, dbEvent.Content)
+			var filters []event.Filter
+			filters = append(filters, trigger.Filters...)
+			filters = append(filters, pipeline.Filters...)
+			pass, reason := event.MatchFilters(filters, dbEvent.Content)
 
 			var eventTrigger = eventclient.EventTrigger{
 				EventName:      dbEvent.Name,
@@ -277,7 +280,7 @@ func (p *Process) process(processEvent eventclient.Event) error {
 				eventTrigger.Message = ""
 			} else {
 				eventTrigger.Status = apistructs.UnPassEventTriggerStatus
-				eventTrigger.Message = fmt.Sprintf("pipeline %v filter not pass", pipeline.Image)
+				eventTrigger.Message = fmt.Sprintf("pipeline %v filter %v not pass", pipeline.Image, reason)
 			}
 			eventTriggers = append(eventTriggers, eventTrigger)
 		}
@@ -320,18 +323,3 @@ func (p *Process) runEventTriggers(eventTriggers []eventclient.EventTrigger) {
 		}
 	}
 }
-
-func checkPass(pipeline event.TriggerPipeline, eventContent string) bool {
-	var pass = true
-
-	for _, filter := range pipeline.Filters {
-		value := gjson.Get(eventContent, filter.Expr).String()
-		for _, match := range filter.Matches {
-			if match == value {
-				pass = true
-				break
-			}
-		}
-	}
-	return pass
-}
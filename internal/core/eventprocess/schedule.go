@@ -0,0 +1,181 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package eventprocess
+
+import (
+	"encoding/json"
+	"eventops/apistructs"
+	"eventops/conf"
+	"eventops/internal/core/client/eventclient"
+	"eventops/internal/core/client/triggerdefinitionclient"
+	"eventops/pkg/cron"
+	"eventops/pkg/schema/event"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"time"
+)
+
+// 一次最多往后找的触发次数, 避免服务停了很久之后计算时间过长
+const maxScheduleTicks = 100000
+
+// LoopSchedule 定期检查声明了 schedule 的触发器, 到了触发时间就生成事件
+func (p *Process) LoopSchedule() {
+	scheduleConf := conf.GetEvent().Schedule
+	if !scheduleConf.Enable {
+		return
+	}
+
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-time.After(time.Duration(scheduleConf.LoopInterval) * time.Second):
+		}
+
+		dbTriggers, err := p.triggerDefinitionClient.ListEventTriggerDefinition(nil, triggerdefinitionclient.ListEventTriggerDefinitionQuery{
+			HasSchedule: true,
+		})
+		if err != nil {
+			logrus.Errorf("[schedule] list schedule trigger definition error: %v", err)
+			continue
+		}
+
+		for index := range dbTriggers {
+			if err := p.scheduleTrigger(&dbTriggers[index], time.Now(), time.Duration(scheduleConf.LoopInterval)*time.Second); err != nil {
+				logrus.Errorf("[schedule] trigger %v creater %v error: %v", dbTriggers[index].Name, dbTriggers[index].Creater, err)
+			}
+		}
+	}
+}
+
+// scheduleStore 记录触发器本次的触发时间, 需要生成事件的时候同时创建事件
+type scheduleStore interface {
+	saveScheduleTick(dbTrigger *triggerdefinitionclient.EventTriggerDefinition, tickTime time.Time, createEvent *eventclient.Event) (bool, error)
+}
+
+func (p *Process) saveScheduleTick(dbTrigger *triggerdefinitionclient.EventTriggerDefinition, tickTime time.Time, createEvent *eventclient.Event) (bool, error) {
+	var updated bool
+	err := p.dbClient.Transaction(func(tx *gorm.DB) error {
+		// 多个 server 同时检查的时候只有一个能更新成功, 保证每次触发只生成一个事件
+		var err error
+		updated, err = p.triggerDefinitionClient.UpdateScheduleLastTime(tx, dbTrigger.Id, dbTrigger.ScheduleLastTime, tickTime)
+		if err != nil {
+			return err
+		}
+		if !updated || createEvent == nil {
+			return nil
+		}
+		_, err = p.eventDbClient.CreateEvent(tx, createEvent)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	return updated, nil
+}
+
+func (p *Process) scheduleTrigger(dbTrigger *triggerdefinitionclient.EventTriggerDefinition, now time.Time, loopInterval time.Duration) error {
+	var trigger event.Trigger
+	if err := yaml.Unmarshal([]byte(dbTrigger.Content), &trigger); err != nil {
+		return fmt.Errorf("unmarshal trigger content error: %v", err)
+	}
+	if trigger.Schedule == nil {
+		return nil
+	}
+
+	schedule, err := cron.Parse(trigger.Schedule.Cron)
+	if err != nil {
+		return err
+	}
+	location, err := trigger.Schedule.GetLocation()
+	if err != nil {
+		return err
+	}
+
+	var lastTime = dbTrigger.CreatedAt
+	if dbTrigger.ScheduleLastTime != nil {
+		lastTime = *dbTrigger.ScheduleLastTime
+	}
+
+	// 找到 now 之前最近的一次触发时间
+	var tickTime time.Time
+	for next, i := schedule.Next(lastTime.In(location)), 0; !next.IsZero() && !next.After(now) && i < maxScheduleTicks; next, i = schedule.Next(next), i+1 {
+		tickTime = next
+	}
+	if tickTime.IsZero() {
+		return nil
+	}
+
+	// skip 策略下超过一个检查周期才发现的触发就认为是错过了, 只记录触发时间不生成事件
+	var fire = true
+	if trigger.Schedule.GetCatchUp() == event.SkipCatchUp {
+		fire = now.Sub(tickTime) <= 2*loopInterval
+	}
+
+	var createEvent *eventclient.Event
+	if fire {
+		createEvent, err = buildScheduleEvent(&trigger, dbTrigger.Creater, tickTime)
+		if err != nil {
+			return err
+		}
+	}
+
+	updated, err := p.scheduleStore.saveScheduleTick(dbTrigger, tickTime, createEvent)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return nil
+	}
+
+	if createEvent != nil {
+		logrus.Infof("[schedule] trigger %v creater %v create event %v at %v", trigger.Name, dbTrigger.Creater, createEvent.Id, tickTime)
+		p.AddToProcess(*createEvent)
+	} else {
+		logrus.Infof("[schedule] trigger %v creater %v skip missed tick %v", trigger.Name, dbTrigger.Creater, tickTime)
+	}
+	return nil
+}
+
+// buildScheduleEvent 定时事件使用触发器专属的事件名称, 只会触发这个触发器自己
+func buildScheduleEvent(trigger *event.Trigger, creater string, tickTime time.Time) (*eventclient.Event, error) {
+	var eventInfo = apistructs.Event{
+		Name:    event.ScheduleEventName(trigger.Name),
+		Version: event.ScheduleEventVersion,
+		Values: map[string]apistructs.Value{
+			"trigger":      apistructs.Value(trigger.Name),
+			"scheduleTime": apistructs.Value(tickTime.Format(time.RFC3339)),
+		},
+		Timestamp:    tickTime.Unix(),
+		SupportUsers: []string{creater},
+	}
+	if err := eventInfo.Check(); err != nil {
+		return nil, err
+	}
+
+	content, err := json.Marshal(eventInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &eventclient.Event{
+		Name:    eventInfo.Name,
+		Version: eventInfo.Version,
+		Content: string(content),
+		Creater: creater,
+		Status:  apistructs.EventCreatedStatus,
+	}, nil
+}
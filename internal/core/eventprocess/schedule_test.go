@@ -0,0 +1,144 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package eventprocess
+
+import (
+	"eventops/internal/core/client/eventclient"
+	"eventops/internal/core/client/triggerdefinitionclient"
+	"eventops/pkg/schema/event"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// fakeScheduleStore 和数据库一样只有 schedule_last_time 没有变化的时候才更新成功
+type fakeScheduleStore struct {
+	lastTime *time.Time
+	events   []*eventclient.Event
+}
+
+func (f *fakeScheduleStore) saveScheduleTick(dbTrigger *triggerdefinitionclient.EventTriggerDefinition, tickTime time.Time, createEvent *eventclient.Event) (bool, error) {
+	if (f.lastTime == nil) != (dbTrigger.ScheduleLastTime == nil) {
+		return false, nil
+	}
+	if f.lastTime != nil && !f.lastTime.Equal(*dbTrigger.ScheduleLastTime) {
+		return false, nil
+	}
+	f.lastTime = &tickTime
+	if createEvent != nil {
+		createEvent.Id = uint64(len(f.events) + 1)
+		f.events = append(f.events, createEvent)
+	}
+	return true, nil
+}
+
+func newScheduleTrigger(catchUp event.CatchUp, lastTime time.Time) *triggerdefinitionclient.EventTriggerDefinition {
+	return &triggerdefinitionclient.EventTriggerDefinition{
+		Id:      1,
+		Name:    "nightly",
+		Creater: "kakj",
+		Content: fmt.Sprintf(`
+name: nightly
+eventCreater: kakj
+eventName: %v
+eventVersion: %v
+schedule:
+  cron: "0 2 * * *"
+  timezone: UTC
+  catchUp: %v
+pipelines:
+  - image: kakj/build:1.0
+`, event.ScheduleEventName("nightly"), event.ScheduleEventVersion, catchUp),
+		Schedule:         "0 2 * * *",
+		ScheduleLastTime: &lastTime,
+	}
+}
+
+func TestScheduleTrigger(t *testing.T) {
+	var loopInterval = 10 * time.Second
+	var lastTime = time.Date(2022, 10, 1, 2, 0, 0, 0, time.UTC)
+	var tickTime = time.Date(2022, 10, 2, 2, 0, 0, 0, time.UTC)
+
+	var testData = []struct {
+		name      string
+		catchUp   event.CatchUp
+		now       time.Time
+		wantTick  time.Time
+		wantEvent bool
+	}{
+		{"not reach", event.SkipCatchUp, tickTime.Add(-time.Second), lastTime, false},
+		{"on time", event.SkipCatchUp, tickTime.Add(time.Second), tickTime, true},
+		{"skip missed", event.SkipCatchUp, tickTime.Add(3 * loopInterval), tickTime, false},
+		{"skip missed days", event.SkipCatchUp, tickTime.Add(48*time.Hour + time.Hour), tickTime.Add(48 * time.Hour), false},
+		{"once missed", event.OnceCatchUp, tickTime.Add(3 * loopInterval), tickTime, true},
+		{"once missed days", event.OnceCatchUp, tickTime.Add(48*time.Hour + time.Hour), tickTime.Add(48 * time.Hour), true},
+	}
+	for _, data := range testData {
+		store := &fakeScheduleStore{lastTime: &lastTime}
+		process := &Process{Buffer: make(chan eventclient.Event, 10), scheduleStore: store}
+
+		if err := process.scheduleTrigger(newScheduleTrigger(data.catchUp, lastTime), data.now, loopInterval); err != nil {
+			t.Fatalf("%v: schedule trigger error: %v", data.name, err)
+		}
+		if !store.lastTime.Equal(data.wantTick) {
+			t.Fatalf("%v: last time %v, want %v", data.name, store.lastTime, data.wantTick)
+		}
+		if data.wantEvent != (len(store.events) == 1) {
+			t.Fatalf("%v: events %v, want event %v", data.name, store.events, data.wantEvent)
+		}
+		if !data.wantEvent {
+			continue
+		}
+
+		// 补触发的事件时间是错过的那次触发时间, 事件名称是触发器专属的名称
+		createEvent := store.events[0]
+		if createEvent.Name != event.ScheduleEventName("nightly") || createEvent.Version != event.ScheduleEventVersion || createEvent.Creater != "kakj" {
+			t.Fatalf("%v: event %v not match", data.name, createEvent)
+		}
+		select {
+		case processEvent := <-process.Buffer:
+			if processEvent.Id != createEvent.Id {
+				t.Fatalf("%v: process event %v, want %v", data.name, processEvent.Id, createEvent.Id)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%v: event not add to process", data.name)
+		}
+	}
+}
+
+func TestScheduleTriggerConcurrent(t *testing.T) {
+	var lastTime = time.Date(2022, 10, 1, 2, 0, 0, 0, time.UTC)
+	var now = time.Date(2022, 10, 2, 2, 0, 1, 0, time.UTC)
+	store := &fakeScheduleStore{lastTime: &lastTime}
+
+	// 两个 server 读到的是同一个 schedule_last_time, 只有一个能生成事件
+	for i := 0; i < 2; i++ {
+		process := &Process{Buffer: make(chan eventclient.Event, 10), scheduleStore: store}
+		if err := process.scheduleTrigger(newScheduleTrigger(event.OnceCatchUp, lastTime), now, 10*time.Second); err != nil {
+			t.Fatalf("schedule trigger error: %v", err)
+		}
+	}
+	if len(store.events) != 1 {
+		t.Fatalf("events %v, want only one", store.events)
+	}
+
+	// 读到新的 schedule_last_time 之后, 同一个触发时间不会再次生成事件
+	process := &Process{Buffer: make(chan eventclient.Event, 10), scheduleStore: store}
+	if err := process.scheduleTrigger(newScheduleTrigger(event.OnceCatchUp, *store.lastTime), now, 10*time.Second); err != nil {
+		t.Fatalf("schedule trigger error: %v", err)
+	}
+	if len(store.events) != 1 {
+		t.Fatalf("events %v, want only one", store.events)
+	}
+}
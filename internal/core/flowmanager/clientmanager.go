@@ -19,7 +19,9 @@ import (
 	"eventops/internal/core/client/pipelineclient"
 	"eventops/internal/core/client/pipelinedefinitionclient"
 	"eventops/internal/core/client/taskclient"
+	"eventops/internal/core/client/tasklogclient"
 	"eventops/internal/core/client/triggerdefinitionclient"
+	"eventops/internal/core/client/userclient"
 	"gorm.io/gorm"
 )
 
@@ -33,6 +35,8 @@ type clientManager struct {
 	pipelineDefinitionClient *pipelinedefinitionclient.Client
 	pipelineClient           *pipelineclient.Client
 	taskClient               *taskclient.Client
+	taskLogClient            *tasklogclient.Client
+	userClient               *userclient.Client
 }
 
 func newClientManager(dbClient *gorm.DB) *clientManager {
@@ -45,5 +49,7 @@ func newClientManager(dbClient *gorm.DB) *clientManager {
 		pipelineDefinitionClient: pipelinedefinitionclient.NewPipelineDefinitionClient(dbClient),
 		pipelineClient:           pipelineclient.NewPipelineClient(dbClient),
 		taskClient:               taskclient.NewTaskClient(dbClient),
+		taskLogClient:            tasklogclient.NewTaskLogClient(dbClient),
+		userClient:               userclient.NewUserClient(dbClient),
 	}
 }
@@ -0,0 +1,277 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"eventops/apistructs"
+	"eventops/internal/core/client/pipelineclient"
+	"eventops/pkg/schema/event"
+	"eventops/pkg/schema/pipeline"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"time"
+)
+
+// findTriggerPipeline 返回触发器中触发这条流水线的声明
+func findTriggerPipeline(data *AssociatedData) (event.TriggerPipeline, error) {
+	var trigger event.Trigger
+	if err := yaml.Unmarshal([]byte(data.triggerDefinition.Content), &trigger); err != nil {
+		return event.TriggerPipeline{}, err
+	}
+
+	var triggerPipe event.TriggerPipeline
+	for _, pipe := range trigger.Pipelines {
+		if pipe.Image == data.eventTrigger.PipelineImage {
+			triggerPipe = pipe
+		}
+	}
+	return triggerPipe, nil
+}
+
+// resolveConcurrency 触发器中声明的 concurrency 优先于流水线定义中的, 组名中的 inputs 占位符使用事件中的值
+func resolveConcurrency(data *AssociatedData, triggerPipe event.TriggerPipeline) (*pipeline.Concurrency, string, error) {
+	var definition pipeline.Pipeline
+	if err := yaml.Unmarshal([]byte(data.pipelineVersionDefinition.Content), &definition); err != nil {
+		return nil, "", err
+	}
+
+	concurrency := definition.Concurrency
+	if triggerPipe.Concurrency != nil {
+		concurrency = triggerPipe.Concurrency
+	}
+	if concurrency == nil {
+		return nil, "", nil
+	}
+
+	var triggerPipeInputMap = make(map[string]string, len(triggerPipe.Inputs))
+	for _, input := range triggerPipe.Inputs {
+		triggerPipeInputMap[input.Name] = gjson.Get(data.event.Content, input.Value).String()
+	}
+
+	var inputs = apistructs.Inputs{}
+	for _, input := range definition.Inputs {
+		inputs[input.Name] = apistructs.Input{
+			Name:  input.Name,
+			Value: triggerPipeInputMap[input.Name],
+			Type:  input.Type,
+		}
+	}
+	return concurrency, concurrency.RenderGroup(inputs), nil
+}
+
+// admitPipeline 根据并发组的策略和运行数量的上限设置新流水线的状态, 只有状态为 running 的流水线需要立即执行
+// 调用方需要持有 concurrencyLock, 直到流水线创建完成
+func (m *FlowManager) admitPipeline(dbPipeline *pipelineclient.Pipeline, dbPipelineExtra *pipelineclient.PipelineExtra, policy pipeline.ConcurrencyPolicy) error {
+	var groupPipelines []pipelineclient.Pipeline
+	if dbPipeline.ConcurrencyGroup != "" {
+		var err error
+		groupPipelines, err = m.clientManager.pipelineClient.ListPipeline(nil, pipelineclient.ListPipelineQuery{
+			Statuses:         []apistructs.PipelineStatus{apistructs.PipelineRunningStatus, apistructs.PipelinePausedStatus, apistructs.PipelineQueuedStatus},
+			ConcurrencyGroup: dbPipeline.ConcurrencyGroup,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// 并发组中有流水线时不需要判断运行数量的上限
+	var usage *runningUsage
+	if len(groupPipelines) == 0 {
+		var err error
+		if usage, err = m.getRunningUsage(); err != nil {
+			return err
+		}
+	}
+
+	cancelPipelines, err := admit(dbPipeline, dbPipelineExtra, policy, groupPipelines, usage)
+	if err != nil {
+		return err
+	}
+	for _, cancelPipeline := range cancelPipelines {
+		err := m.cancelGroupPipeline(cancelPipeline, fmt.Sprintf("canceled by a new pipeline in concurrency group %v", dbPipeline.ConcurrencyGroup))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// admit 设置新流水线的状态, 返回并发组中需要取消的流水线, groupPipelines 为空时使用 usage 判断是否需要排队
+func admit(dbPipeline *pipelineclient.Pipeline, dbPipelineExtra *pipelineclient.PipelineExtra, policy pipeline.ConcurrencyPolicy,
+	groupPipelines []pipelineclient.Pipeline, usage *runningUsage) ([]*pipelineclient.Pipeline, error) {
+	dbPipeline.Status = apistructs.PipelineRunningStatus
+
+	if len(groupPipelines) > 0 {
+		var cancelPipelines []*pipelineclient.Pipeline
+		switch policy {
+		case pipeline.SkipConcurrencyPolicy:
+			now := time.Now()
+			dbPipeline.Status = apistructs.PipelineCancelStatus
+			dbPipeline.TimeBegin = &now
+			dbPipeline.TimeEnd = &now
+			dbPipelineExtra.Extra.StopReason = fmt.Sprintf("skipped, concurrency group %v has running, paused or queued pipeline", dbPipeline.ConcurrencyGroup)
+			return nil, nil
+		case pipeline.CancelPreviousConcurrencyPolicy:
+			for index := range groupPipelines {
+				cancelPipelines = append(cancelPipelines, &groupPipelines[index])
+			}
+		}
+
+		// 运行中的流水线结束后才会执行排队的流水线
+		dbPipeline.Status = apistructs.PipelineQueuedStatus
+		return cancelPipelines, nil
+	}
+
+	reason, err := usage.waitReason(dbPipeline)
+	if err != nil {
+		return nil, err
+	}
+	if reason != "" {
+		dbPipeline.Status = apistructs.PipelineQueuedStatus
+	}
+	return nil, nil
+}
+
+func (m *FlowManager) cancelGroupPipeline(dbPipeline *pipelineclient.Pipeline, reason string) error {
+	if dbPipeline.Status.IsQueued() {
+		return m.cancelQueuedPipeline(dbPipeline, reason)
+	}
+
+	flow := m.GetFlow(dbPipeline.Id)
+	if flow == nil && dbPipeline.Status.IsPaused() {
+		var err error
+		if flow, err = m.loadPausedFlow(dbPipeline); err != nil {
+			return err
+		}
+	}
+	if flow != nil {
+		flow.lazyStopPipeline(apistructs.PipelineCancelStatus, reason)
+	}
+	return nil
+}
+
+func (m *FlowManager) cancelQueuedPipeline(dbPipeline *pipelineclient.Pipeline, reason string) error {
+	return m.endQueuedPipeline(dbPipeline, apistructs.PipelineCancelStatus, reason)
+}
+
+// endQueuedPipeline 结束还没有开始运行的流水线, 记录开始结束时间和原因
+func (m *FlowManager) endQueuedPipeline(dbPipeline *pipelineclient.Pipeline, status apistructs.PipelineStatus, reason string) error {
+	dbPipelineExtra, find, err := m.clientManager.pipelineClient.GetPipelineExtra(nil, dbPipeline.Id)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	dbPipeline.Status = status
+	dbPipeline.TimeBegin = &now
+	dbPipeline.TimeEnd = &now
+	return m.clientManager.db.Transaction(func(tx *gorm.DB) error {
+		// 没有 extra 的流水线也需要结束, 否则会一直排队
+		if find {
+			if dbPipelineExtra.Extra == nil {
+				dbPipelineExtra.Extra = &pipelineclient.PipelineExtraInfo{}
+			}
+			dbPipelineExtra.Extra.StopReason = reason
+			if _, err := m.clientManager.pipelineClient.UpdatePipelineExtra(tx, dbPipelineExtra); err != nil {
+				return err
+			}
+		}
+		_, err := m.clientManager.pipelineClient.UpdatePipeline(tx, dbPipeline)
+		return err
+	})
+}
+
+// CancelQueuedPipeline 取消还在排队的流水线
+func (m *FlowManager) CancelQueuedPipeline(id uint64, user string) error {
+	m.concurrencyLock.Lock()
+	defer m.concurrencyLock.Unlock()
+
+	dbPipeline, find, err := m.clientManager.pipelineClient.GetPipeline(nil, id, user)
+	if err != nil {
+		return err
+	}
+	if !find {
+		return fmt.Errorf("not find this runtime: %v", id)
+	}
+	if !dbPipeline.Status.IsQueued() {
+		return fmt.Errorf("pipeline %v status %v not %v", id, dbPipeline.Status, apistructs.PipelineQueuedStatus)
+	}
+
+	return m.cancelQueuedPipeline(dbPipeline, fmt.Sprintf("user: %v stop", user))
+}
+
+// scheduleQueuedPipelines 按照优先级和用户的公平份额执行排队的流水线, 同一个并发组中只运行一条, 并且不超过全局和用户的运行数量上限
+func (m *FlowManager) scheduleQueuedPipelines() {
+	m.concurrencyLock.Lock()
+	defer m.concurrencyLock.Unlock()
+
+	queuedPipelines, err := m.clientManager.pipelineClient.ListPipeline(nil, pipelineclient.ListPipelineQuery{
+		Statuses:     []apistructs.PipelineStatus{apistructs.PipelineQueuedStatus},
+		OrderByIdAsc: true,
+	})
+	if err != nil {
+		logrus.Errorf("list queued pipeline error: %v", err)
+		return
+	}
+	if len(queuedPipelines) == 0 {
+		return
+	}
+
+	usage, err := m.getRunningUsage()
+	if err != nil {
+		logrus.Errorf("get running pipeline usage error: %v", err)
+		return
+	}
+
+	runPipelines, _, err := usage.plan(queuedPipelines)
+	if err != nil {
+		logrus.Errorf("plan queued pipeline error: %v", err)
+		return
+	}
+
+	for _, dbPipeline := range runPipelines {
+		if err := m.runQueuedPipeline(dbPipeline); err != nil {
+			logrus.Errorf("run queued pipeline %v error: %v", dbPipeline.Id, err)
+			// 无法执行的流水线标记为失败, 继续执行下一条
+			if err := m.endQueuedPipeline(dbPipeline, apistructs.PipelineFailedStatus, fmt.Sprintf("run queued pipeline error: %v", err)); err != nil {
+				logrus.Errorf("update queued pipeline %v status error: %v", dbPipeline.Id, err)
+			}
+		}
+	}
+}
+
+func (m *FlowManager) runQueuedPipeline(dbPipeline *pipelineclient.Pipeline) error {
+	dbPipelineExtra, find, err := m.clientManager.pipelineClient.GetPipelineExtra(nil, dbPipeline.Id)
+	if err != nil {
+		return err
+	}
+	if !find {
+		return fmt.Errorf("not find pipelineId: %v pipelineExtra", dbPipeline.Id)
+	}
+
+	dbPipeline.Status = apistructs.PipelineRunningStatus
+	flow, err := newFlow(m, dbPipeline, dbPipelineExtra)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.clientManager.pipelineClient.UpdatePipeline(nil, dbPipeline); err != nil {
+		return err
+	}
+	go m.runFlow(flow)
+	return nil
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"eventops/apistructs"
+	"eventops/internal/core/client/pipelineclient"
+	"eventops/pkg/schema/pipeline"
+	"reflect"
+	"testing"
+)
+
+func TestAdmit(t *testing.T) {
+	var groupPipelines = []pipelineclient.Pipeline{
+		{Id: 1, Creater: "a", ConcurrencyGroup: "deploy", Status: apistructs.PipelineRunningStatus},
+		{Id: 2, Creater: "a", ConcurrencyGroup: "deploy", Status: apistructs.PipelineQueuedStatus},
+	}
+	var quotas = map[string]userQuota{"a": {weight: 1}}
+
+	var testData = []struct {
+		name           string
+		policy         pipeline.ConcurrencyPolicy
+		groupPipelines []pipelineclient.Pipeline
+		usage          *runningUsage
+		wantStatus     apistructs.PipelineStatus
+		wantCancel     []uint64
+	}{
+		{"run", pipeline.QueueConcurrencyPolicy, nil, newTestUsage(0, quotas), apistructs.PipelineRunningStatus, nil},
+		{"max running queue", pipeline.SkipConcurrencyPolicy, nil, newTestUsage(1, quotas, pipelineclient.Pipeline{Creater: "a"}), apistructs.PipelineQueuedStatus, nil},
+		{"group queue", pipeline.QueueConcurrencyPolicy, groupPipelines, nil, apistructs.PipelineQueuedStatus, nil},
+		{"group cancel previous", pipeline.CancelPreviousConcurrencyPolicy, groupPipelines, nil, apistructs.PipelineQueuedStatus, []uint64{1, 2}},
+		{"group skip", pipeline.SkipConcurrencyPolicy, groupPipelines, nil, apistructs.PipelineCancelStatus, nil},
+	}
+	for _, data := range testData {
+		dbPipeline := &pipelineclient.Pipeline{Id: 3, Creater: "a", ConcurrencyGroup: "deploy"}
+		dbPipelineExtra := &pipelineclient.PipelineExtra{Extra: &pipelineclient.PipelineExtraInfo{}}
+
+		cancelPipelines, err := admit(dbPipeline, dbPipelineExtra, data.policy, data.groupPipelines, data.usage)
+		if err != nil {
+			t.Fatalf("%v: admit error: %v", data.name, err)
+		}
+		if dbPipeline.Status != data.wantStatus {
+			t.Fatalf("%v: status %v, want %v", data.name, dbPipeline.Status, data.wantStatus)
+		}
+		if got := pipelineIds(cancelPipelines); !reflect.DeepEqual(got, data.wantCancel) {
+			t.Fatalf("%v: cancel %v, want %v", data.name, got, data.wantCancel)
+		}
+
+		// 跳过的流水线直接结束, 需要记录开始结束时间和原因
+		skipped := dbPipeline.TimeBegin != nil && dbPipeline.TimeEnd != nil && dbPipelineExtra.Extra.StopReason != ""
+		if skipped != (data.wantStatus == apistructs.PipelineCancelStatus) {
+			t.Fatalf("%v: pipeline %v extra %v", data.name, dbPipeline, dbPipelineExtra.Extra)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"context"
+	"eventops/apistructs"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strings"
+)
+
+// runFinally dag 结束后使用新的 ctx 按顺序执行 finally 任务, 任务失败不影响后续 finally 任务和流水线的状态
+func (p *Flow) runFinally() {
+	definition, err := p.getAndSetPipelineVersionDefinition(p.rootNode.image)
+	if err != nil {
+		logrus.Errorf("pipeline %v get definition error: %v, finally tasks not run", p.getPipe().Id, err)
+		return
+	}
+	if len(definition.Finally) == 0 {
+		return
+	}
+
+	// dag 的 ctx 已经被取消, 所有 dag 任务都已经退出
+	finallyCtx, finallyCancel := context.WithCancel(context.Background())
+	defer finallyCancel()
+	p.lock.Lock()
+	p.ctx = finallyCtx
+	p.cancel = finallyCancel
+	if p.finallyCanceled {
+		finallyCancel()
+	}
+	p.lock.Unlock()
+
+	var finallyErrors []string
+	for index := range definition.Finally {
+		taskDefinition := &definition.Finally[index]
+		if finallyCtx.Err() != nil {
+			break
+		}
+
+		err := p.rootNode.createDbTask(0, taskDefinition.Alias, taskDefinition.Type, nil)
+		if err != nil {
+			finallyErrors = append(finallyErrors, fmt.Sprintf("task alias: %v create error: %v", taskDefinition.Alias, err))
+			continue
+		}
+
+		node := NewNode(p, 0, taskDefinition, p.rootNode.image)
+		err = node.Run()
+		if err != nil {
+			logrus.Debugf("pipeline: %v finally task: %v run error: %v", p.getPipe().Id, taskDefinition.Alias, err)
+		}
+
+		task := node.getTask()
+		if task.Status.IsFailedStatus() {
+			finallyErrors = append(finallyErrors, fmt.Sprintf("task alias: %v status: %v %v", task.Alias, task.Status, task.Extra.Error))
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if finallyCtx.Err() != nil {
+		finallyErrors = append(finallyErrors, "finally tasks canceled")
+		p.dbPipeExtra.Extra.FinallyStatus = apistructs.CancelTaskStatus
+		p.dbPipeExtra.Extra.FinallyError = strings.Join(finallyErrors, "; ")
+	} else if len(finallyErrors) > 0 {
+		p.dbPipeExtra.Extra.FinallyStatus = apistructs.FailedTaskStatus
+		p.dbPipeExtra.Extra.FinallyError = strings.Join(finallyErrors, "; ")
+	} else {
+		p.dbPipeExtra.Extra.FinallyStatus = apistructs.SuccessTaskStatus
+		p.dbPipeExtra.Extra.FinallyError = ""
+	}
+}
+
+// cancelFinally 流水线停止后取消正在执行或者还没有开始执行的 finally 任务, 流水线的状态不变
+func (p *Flow) cancelFinally(callback func()) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// lazyStopFunc 为空代表 finally 任务已经执行完成, 流水线的状态已经保存
+	if p.lazyStopFunc == nil {
+		return
+	}
+	if callback != nil {
+		lazyStopFunc := p.lazyStopFunc
+		p.lazyStopFunc = func() {
+			lazyStopFunc()
+			callback()
+		}
+	}
+	p.finallyCanceled = true
+	p.cancel()
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"context"
+	"eventops/apistructs"
+	"testing"
+)
+
+func TestCancelFinally(t *testing.T) {
+	dagCtx, dagCancel := context.WithCancel(context.Background())
+	flow := &Flow{ctx: dagCtx, cancel: dagCancel}
+
+	if !flow.lazyStopPipelineWithCallback(apistructs.PipelineFailedStatus, "task failed", nil) {
+		t.Fatalf("first stop should stop pipeline")
+	}
+	if dagCtx.Err() == nil {
+		t.Fatalf("dag ctx should be canceled after stop")
+	}
+
+	// 和 runFinally 一样替换 ctx, 替换后的 ctx 需要通过 getCtx 读取
+	finallyCtx, finallyCancel := context.WithCancel(context.Background())
+	flow.lock.Lock()
+	flow.ctx = finallyCtx
+	flow.cancel = finallyCancel
+	var saved bool
+	flow.lazyStopFunc = func() {
+		saved = true
+	}
+	flow.lock.Unlock()
+	if flow.getCtx() != finallyCtx {
+		t.Fatalf("getCtx should return finally ctx")
+	}
+
+	var called bool
+	if flow.lazyStopPipelineWithCallback(apistructs.PipelineCancelStatus, "user stop", func() { called = true }) {
+		t.Fatalf("stopped pipeline should not stop again")
+	}
+	flow.cancelFinally(func() { called = true })
+	if finallyCtx.Err() == nil || !flow.finallyCanceled {
+		t.Fatalf("finally ctx should be canceled")
+	}
+	if called {
+		t.Fatalf("callback should run after pipeline status saved")
+	}
+
+	flow.runLazyStopFunc()
+	if !saved || !called {
+		t.Fatalf("lazy stop func %v callback %v should both run", saved, called)
+	}
+
+	// 流水线的状态保存后再取消不会调用 callback
+	called = false
+	flow.cancelFinally(func() { called = true })
+	if called {
+		t.Fatalf("callback should not run after pipeline status saved")
+	}
+}
@@ -16,9 +16,11 @@ package flowmanager
 import (
 	"context"
 	"eventops/apistructs"
+	"eventops/conf"
 	"eventops/internal/core/client/pipelineclient"
 	"eventops/internal/core/client/taskclient"
 	"eventops/pkg/dag"
+	"eventops/pkg/schema/event"
 	"eventops/pkg/schema/pipeline"
 	"fmt"
 	"github.com/sirupsen/logrus"
@@ -36,6 +38,12 @@ type Flow struct {
 
 	stopOnce     sync.Once
 	lazyStopFunc func()
+	stopStatus   apistructs.PipelineStatus
+	// finallyCanceled 流水线停止后再次取消时为 true, finally 任务会被取消
+	finallyCanceled bool
+
+	// pauseChan 不为空时流水线处于暂停状态, 恢复时关闭
+	pauseChan chan struct{}
 
 	rootNode *Node
 	nodes    map[uint64]*Node
@@ -75,6 +83,9 @@ func newFlow(flowManager *FlowManager, dbPipe *pipelineclient.Pipeline, dbPipeEx
 		dbPipeExtra: dbPipeExtra,
 		dbPipe:      dbPipe,
 	}
+	if dbPipe.Status.IsPaused() {
+		runPipeline.pauseChan = make(chan struct{})
+	}
 
 	rootTask := taskclient.Task{
 		Alias:        dag.Root,
@@ -191,6 +202,30 @@ func WithJobSign(jobSign string) Opt {
 	}
 }
 
+// WithAttempt 记录本次执行的结果, 并将任务重置为初始化状态等待重新创建
+func WithAttempt(attempt apistructs.TaskAttempt) Opt {
+	return func(task *taskclient.Task) {
+		task.Extra.Attempts = append(task.Extra.Attempts, attempt)
+		task.Extra.AttemptTimeBegin = nil
+		task.Extra.Error = ""
+		task.Status = apistructs.InitTaskStatus
+		task.JobSign = ""
+		task.TimeEnd = nil
+	}
+}
+
+func WithAttemptTimeBegin(timeBegin time.Time) Opt {
+	return func(task *taskclient.Task) {
+		task.Extra.AttemptTimeBegin = &timeBegin
+	}
+}
+
+func WithApproval(approval *apistructs.TaskApproval) Opt {
+	return func(task *taskclient.Task) {
+		task.Extra.Approval = approval
+	}
+}
+
 func (p *Flow) setTask(parentTaskId uint64, taskAlias string, opts ...Opt) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
@@ -300,6 +335,82 @@ func (p *Flow) getAndSetPipelineVersionDefinition(image string) (*pipeline.Pipel
 	return &pipelineDefinition, nil
 }
 
+// addWarning 记录被忽略的任务失败, 流水线结束时和状态一起保存
+func (p *Flow) addWarning(warning string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for _, value := range p.dbPipeExtra.Extra.Warnings {
+		if value == warning {
+			return
+		}
+	}
+	p.dbPipeExtra.Extra.Warnings = append(p.dbPipeExtra.Extra.Warnings, warning)
+}
+
+// getCtx dag 结束后执行 finally 任务时 ctx 会被替换, 需要加锁读取
+func (p *Flow) getCtx() context.Context {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.ctx
+}
+
+func (p *Flow) getStopStatus() apistructs.PipelineStatus {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.stopStatus
+}
+
+// getTriggerPipeline 返回触发器定义中触发当前流水线的配置
+func (p *Flow) getTriggerPipeline() (event.TriggerPipeline, error) {
+	var triggerDefinitionPipe event.TriggerPipeline
+
+	dbTriggerDefinition := p.getPipeExtra().TriggerDefinitionContent
+	if dbTriggerDefinition == nil {
+		return triggerDefinitionPipe, nil
+	}
+
+	var triggerDefinition event.Trigger
+	err := yaml.Unmarshal([]byte(dbTriggerDefinition.Content), &triggerDefinition)
+	if err != nil {
+		return triggerDefinitionPipe, err
+	}
+
+	for _, pipe := range triggerDefinition.Pipelines {
+		if pipe.Image == p.rootNode.image {
+			triggerDefinitionPipe = pipe
+		}
+	}
+	return triggerDefinitionPipe, nil
+}
+
+// getTimeout 触发器中声明的超时时间优先, 其次是流水线定义中的, 都没有声明时使用服务端的默认值, 不能超过服务端的最大值
+func (p *Flow) getTimeout() time.Duration {
+	timeoutConf := conf.GetPipeline().Timeout
+
+	var timeout = timeoutConf.Default
+	definition, err := p.getAndSetPipelineVersionDefinition(p.rootNode.image)
+	if err != nil {
+		logrus.Errorf("pipeline %v get definition error: %v, use default timeout", p.getPipe().Id, err)
+	} else if definition.Timeout > 0 {
+		timeout = definition.Timeout
+	}
+
+	triggerDefinitionPipe, err := p.getTriggerPipeline()
+	if err != nil {
+		logrus.Errorf("pipeline %v get trigger definition error: %v", p.getPipe().Id, err)
+	} else if triggerDefinitionPipe.Timeout > 0 {
+		timeout = triggerDefinitionPipe.Timeout
+	}
+
+	if timeoutConf.Max > 0 && timeout > timeoutConf.Max {
+		timeout = timeoutConf.Max
+	}
+	return time.Duration(timeout) * time.Second
+}
+
 func (p *Flow) runLazyStopFunc() {
 	p.lock.Lock()
 	defer p.lock.Unlock()
@@ -317,19 +428,11 @@ func (p *Flow) run() {
 			p.lazyStopPipeline(apistructs.PipelineFailedStatus, "panic error")
 		}
 		p.lazyStopPipeline(apistructs.PipelineSuccessStatus, "")
+		p.runFinally()
 		p.runLazyStopFunc()
 		logrus.Debugf("pipeline %v stop", p.getPipe().Id)
 	}()
 
-	go func() {
-		select {
-		case <-p.ctx.Done():
-			return
-		case <-time.After(24 * time.Hour):
-			p.lazyStopPipeline(apistructs.PipelineFailedStatus, "timeout")
-		}
-	}()
-
 	dbTasks, err := p.flowManager.clientManager.taskClient.ListTasks(nil, p.getPipe().Id, p.getPipe().Creater)
 	if err != nil {
 		p.lazyStopPipeline(apistructs.PipelineFailedStatus, err.Error())
@@ -348,6 +451,32 @@ func (p *Flow) run() {
 			return
 		}
 	}
+
+	// 超时时间从流水线开始执行计算, 服务重启后不会重新计时, 暂停的时间不计入超时
+	ctx := p.getCtx()
+	timeout := p.getTimeout()
+	go func() {
+		for {
+			duration, running := p.runningDuration()
+			if !running {
+				if !p.waitResume() {
+					return
+				}
+				continue
+			}
+			if duration >= timeout {
+				p.lazyStopPipeline(apistructs.PipelineTimeoutStatus, fmt.Sprintf("pipeline timeout after %v", timeout))
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(timeout - duration):
+			}
+		}
+	}()
+
 	err = p.rootNode.Run()
 	if err != nil {
 		logrus.Debugf("pipeline: %v run error: %v", p.getPipe().Id, err)
@@ -358,13 +487,17 @@ func (p *Flow) lazyStopPipeline(status apistructs.PipelineStatus, stopReason str
 	p.lazyStopPipelineWithCallback(status, stopReason, nil)
 }
 
-func (p *Flow) lazyStopPipelineWithCallback(status apistructs.PipelineStatus, stopReason string, callback func()) {
+// lazyStopPipelineWithCallback 返回是否是这次调用停止了流水线
+func (p *Flow) lazyStopPipelineWithCallback(status apistructs.PipelineStatus, stopReason string, callback func()) bool {
 	if !status.IsEnd() {
-		return
+		return false
 	}
 
+	var stopped bool
 	p.stopOnce.Do(func() {
+		stopped = true
 		p.lock.Lock()
+		p.stopStatus = status
 		p.lazyStopFunc = func() {
 			p.dbPipe.Status = status
 			p.dbPipeExtra.Extra.StopReason = stopReason
@@ -388,15 +521,20 @@ func (p *Flow) lazyStopPipelineWithCallback(status apistructs.PipelineStatus, st
 			})
 			if err != nil {
 				logrus.Errorf("failed to update pipeline status: %v error: %v", apistructs.PipelineFailedStatus, err.Error())
+			} else {
+				// lazyStopFunc 执行的时候持有 flow 的锁
+				go p.publishFinishedEvent()
 			}
 
 			if callback != nil {
 				callback()
 			}
 		}
+		cancel := p.cancel
 		p.lock.Unlock()
-		p.cancel()
+		cancel()
 	})
+	return stopped
 }
 
 func (p *Flow) clear() {
@@ -36,8 +36,14 @@ type FlowManager struct {
 	flows map[uint64]*Flow
 	lock  sync.Mutex
 
+	// concurrencyLock 保证同一时间只有一个地方在决定并发组中流水线的状态
+	concurrencyLock sync.Mutex
+
+	actuatorLimiter *actuatorLimiter
+
 	clientManager *clientManager
 	dialerServer  *dialer.Server
+	eventHandler  EventHandler
 }
 
 func NewFlowManager(parentCtx context.Context, client *gorm.DB, dialerServer *dialer.Server) *FlowManager {
@@ -47,11 +53,14 @@ func NewFlowManager(parentCtx context.Context, client *gorm.DB, dialerServer *di
 		ctx:   parentCtx,
 		flows: map[uint64]*Flow{},
 
+		actuatorLimiter: newActuatorLimiter(),
+
 		clientManager: clientManager,
 		dialerServer:  dialerServer,
 	}
 }
 
+// Run 恢复运行中和排队的流水线, 暂停的流水线需要手动恢复
 func (m *FlowManager) Run() error {
 	runningPipelines, err := m.clientManager.pipelineClient.ListPipeline(nil, pipelineclient.ListPipelineQuery{
 		Statuses: []apistructs.PipelineStatus{
@@ -88,6 +97,9 @@ func (m *FlowManager) Run() error {
 			}, index)
 		}
 		worker.Do()
+
+		// 服务启动时恢复排队中的流水线
+		m.scheduleQueuedPipelines()
 	}()
 
 	return nil
@@ -103,6 +115,8 @@ func (m *FlowManager) runFlow(flow *Flow) {
 	m.lock.Lock()
 	delete(m.flows, flow.dbPipe.Id)
 	m.lock.Unlock()
+
+	m.scheduleQueuedPipelines()
 }
 
 func (m *FlowManager) GetFlow(id uint64) *Flow {
@@ -122,7 +136,29 @@ func (m *FlowManager) CancelFlow(id uint64, user string, callback func()) {
 		return
 	}
 
-	flow.lazyStopPipelineWithCallback(apistructs.PipelineCancelStatus, fmt.Sprintf("user: %v stop", user), callback)
+	if !flow.lazyStopPipelineWithCallback(apistructs.PipelineCancelStatus, fmt.Sprintf("user: %v stop", user), callback) {
+		// 流水线已经停止, 取消正在执行的 finally 任务
+		flow.cancelFinally(callback)
+	}
+}
+
+// ApproveTask 审批运行中流水线的 approval 任务, approved 为 false 时拒绝
+func (m *FlowManager) ApproveTask(pipelineId uint64, taskId uint64, user string, approved bool, comment string) error {
+	flow := m.GetFlow(pipelineId)
+	if flow == nil {
+		return fmt.Errorf("not find running pipeline %v", pipelineId)
+	}
+
+	node := flow.getNode(taskId)
+	if node == nil {
+		return fmt.Errorf("not find running task %v in pipeline %v", taskId, pipelineId)
+	}
+
+	if node.getTask().Type != apistructs.ApprovalType {
+		return fmt.Errorf("task %v type not %v", taskId, apistructs.ApprovalType)
+	}
+
+	return node.approve(user, approved, comment)
 }
 
 func (m *FlowManager) Callback(body apistructs.CallbackBody) error {
@@ -186,6 +222,11 @@ func (m *FlowManager) RunFlowByEventTrigger(id uint64) error {
 			return nil
 		}
 
+		// 排队的流水线由并发组调度执行
+		if dbPipeline.Status.IsQueued() {
+			return m.clientManager.eventTriggerClient.UpdateEventTriggerStatus(nil, eventTrigger.Id, eventTrigger.Status, apistructs.ProcessedEventTriggerStatus, "")
+		}
+
 		err = m.clientManager.db.Transaction(func(tx *gorm.DB) error {
 			err = m.clientManager.eventTriggerClient.UpdateEventTriggerStatus(nil, eventTrigger.Id, eventTrigger.Status, apistructs.ProcessedEventTriggerStatus, "")
 			if err != nil {
@@ -208,6 +249,16 @@ func (m *FlowManager) RunFlowByEventTrigger(id uint64) error {
 			return err
 		}
 
+		triggerPipe, err := findTriggerPipeline(&associatedData)
+		if err != nil {
+			return err
+		}
+
+		concurrency, concurrencyGroup, err := resolveConcurrency(&associatedData, triggerPipe)
+		if err != nil {
+			return err
+		}
+
 		dbPipeline = &pipelineclient.Pipeline{
 			EventTriggerId:      eventTrigger.Id,
 			EventId:             eventTrigger.EventId,
@@ -218,9 +269,22 @@ func (m *FlowManager) RunFlowByEventTrigger(id uint64) error {
 			Creater:             eventTrigger.TriggerCreater,
 			Status:              apistructs.PipelineRunningStatus,
 			CostTimeSec:         0,
+			ConcurrencyGroup:    concurrencyGroup,
+			Priority:            resolvePriority(&associatedData, triggerPipe),
 		}
 		dbPipelineExtra = buildPipelineExtra(&associatedData)
 
+		m.concurrencyLock.Lock()
+		defer m.concurrencyLock.Unlock()
+
+		var policy pipeline.ConcurrencyPolicy
+		if concurrency != nil {
+			policy = concurrency.GetPolicy()
+		}
+		if err := m.admitPipeline(dbPipeline, dbPipelineExtra, policy); err != nil {
+			return err
+		}
+
 		err = m.clientManager.db.Transaction(func(tx *gorm.DB) error {
 			dbPipeline, err := m.clientManager.pipelineClient.CreatePipeline(tx, dbPipeline)
 			if err != nil {
@@ -238,6 +302,10 @@ func (m *FlowManager) RunFlowByEventTrigger(id uint64) error {
 				return err
 			}
 
+			if !dbPipeline.Status.IsRunning() {
+				return nil
+			}
+
 			flow, err = newFlow(m, dbPipeline, dbPipelineExtra)
 			if err != nil {
 				return err
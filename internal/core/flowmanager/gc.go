@@ -0,0 +1,229 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"context"
+	"errors"
+	"eventops/apistructs"
+	"eventops/conf"
+	"eventops/internal/core/actuator"
+	"eventops/internal/core/client/pipelineclient"
+	"eventops/pkg/schema/pipeline"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+)
+
+const gcBatchSize = 100
+
+// gcFailedBackoffRounds 回收失败的流水线跳过多少轮, 避免失败的流水线一直占满每一批
+const gcFailedBackoffRounds = 10
+
+// LoopGc 定期回收已经结束的流水线在执行器上的资源
+func (m *FlowManager) LoopGc() {
+	gcConf := conf.GetPipeline().Gc
+	if !gcConf.Enable {
+		return
+	}
+
+	var failedAt = map[uint64]time.Time{}
+	var failedBackoff = time.Duration(gcConf.Interval*gcFailedBackoffRounds) * time.Second
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-time.After(time.Duration(gcConf.Interval) * time.Second):
+		}
+
+		var excludeIds []uint64
+		for id, t := range failedAt {
+			if time.Since(t) > failedBackoff {
+				delete(failedAt, id)
+				continue
+			}
+			excludeIds = append(excludeIds, id)
+		}
+
+		pipelines, err := m.listNeedGcPipelines(excludeIds)
+		if err != nil {
+			logrus.Errorf("[gc] list need gc pipelines error: %v", err)
+			continue
+		}
+
+		for _, dbPipeline := range pipelines {
+			if err := m.CleanPipeline(&dbPipeline); err != nil {
+				failedAt[dbPipeline.Id] = time.Now()
+				logrus.Errorf("[gc] clean pipeline %v error: %v", dbPipeline.Id, err)
+			}
+		}
+	}
+}
+
+// listNeedGcPipelines 按照 id 正序取出需要回收的流水线, 最近回收失败的流水线会被排除
+func (m *FlowManager) listNeedGcPipelines(excludeIds []uint64) ([]pipelineclient.Pipeline, error) {
+	gcConf := conf.GetPipeline().Gc
+
+	var failedStatuses []apistructs.PipelineStatus
+	for _, status := range apistructs.EndPipelineStatuses {
+		if status != apistructs.PipelineSuccessStatus {
+			failedStatuses = append(failedStatuses, status)
+		}
+	}
+
+	var queries []pipelineclient.ListPipelineQuery
+	if gcConf.SuccessRetention > 0 {
+		queries = append(queries, pipelineclient.ListPipelineQuery{
+			Statuses:      []apistructs.PipelineStatus{apistructs.PipelineSuccessStatus},
+			TimeEndBefore: &[]time.Time{time.Now().Add(-time.Duration(gcConf.SuccessRetention) * time.Second)}[0],
+		})
+	}
+	if gcConf.FailedRetention > 0 {
+		queries = append(queries, pipelineclient.ListPipelineQuery{
+			Statuses:      failedStatuses,
+			TimeEndBefore: &[]time.Time{time.Now().Add(-time.Duration(gcConf.FailedRetention) * time.Second)}[0],
+		})
+	}
+	if gcConf.MaxAge > 0 {
+		queries = append(queries, pipelineclient.ListPipelineQuery{
+			Statuses:      apistructs.EndPipelineStatuses,
+			CreatedBefore: &[]time.Time{time.Now().Add(-time.Duration(gcConf.MaxAge) * time.Second)}[0],
+		})
+	}
+
+	var result []pipelineclient.Pipeline
+	var pipelineIdMap = map[uint64]bool{}
+	for _, query := range queries {
+		query.HasUncleanedTask = true
+		query.Top = gcBatchSize
+		query.OrderByIdAsc = true
+		query.ExcludeIds = excludeIds
+
+		pipelines, err := m.clientManager.pipelineClient.ListPipeline(nil, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, dbPipeline := range pipelines {
+			if pipelineIdMap[dbPipeline.Id] {
+				continue
+			}
+			pipelineIdMap[dbPipeline.Id] = true
+			result = append(result, dbPipeline)
+		}
+	}
+	return result, nil
+}
+
+// CleanPipeline 调用执行器的 Remove 回收每个任务的资源, 所有执行器的 RemovePipeline 都成功后才记录任务的 cleaned 标识
+func (m *FlowManager) CleanPipeline(dbPipeline *pipelineclient.Pipeline) error {
+	if !dbPipeline.Status.IsEnd() {
+		return fmt.Errorf("pipeline %v status %v not end", dbPipeline.Id, dbPipeline.Status)
+	}
+
+	if m.GetFlow(dbPipeline.Id) != nil {
+		return fmt.Errorf("pipeline %v is stopping", dbPipeline.Id)
+	}
+
+	tasks, err := m.clientManager.taskClient.ListTasks(nil, dbPipeline.Id, dbPipeline.Creater)
+	if err != nil {
+		return err
+	}
+
+	var pipelineId = strconv.FormatUint(dbPipeline.Id, 10)
+	var actuators = map[string]actuator.Actuator{}
+	var missingActuators = map[string]bool{}
+	var cleanedIds []uint64
+	var cleanErr error
+	for _, task := range tasks {
+		if task.Type == apistructs.PipeType || task.Extra == nil || task.Extra.ChooseTag == "" {
+			if !task.Cleaned {
+				cleanedIds = append(cleanedIds, task.Id)
+			}
+			continue
+		}
+
+		// 已经回收过的任务也需要获取执行器, 之前失败的 RemovePipeline 需要再次调用
+		var actuatorKey = fmt.Sprintf("%v-%v", task.Type, task.Extra.ChooseTag)
+		runner, ok := actuators[actuatorKey]
+		if !ok && !missingActuators[actuatorKey] {
+			runner, err = m.getTagActuator(task.Creater, task.Type, task.Extra.ChooseTag)
+			if err != nil {
+				if !errors.Is(err, actuatorNotFindError) {
+					cleanErr = err
+					continue
+				}
+				logrus.Warnf("[gc] pipeline %v actuator %v not exist, skip clean: %v", dbPipeline.Id, actuatorKey, err)
+				missingActuators[actuatorKey] = true
+			} else {
+				actuators[actuatorKey] = runner
+			}
+		}
+
+		if task.Cleaned {
+			continue
+		}
+		if task.JobSign == "" || missingActuators[actuatorKey] {
+			cleanedIds = append(cleanedIds, task.Id)
+			continue
+		}
+
+		job := &actuator.Job{
+			PipelineId:     pipelineId,
+			TaskId:         strconv.FormatUint(task.Id, 10),
+			DefinitionTask: &pipeline.Task{Alias: task.Alias, Type: task.Type},
+			JobSign:        task.JobSign,
+		}
+		if err := removeJob(runner, job); err != nil {
+			cleanErr = fmt.Errorf("remove task %v job %v error: %v", task.Id, task.JobSign, err)
+			continue
+		}
+		cleanedIds = append(cleanedIds, task.Id)
+	}
+
+	var removePipelineErr error
+	for key, runner := range actuators {
+		remover, ok := runner.(actuator.PipelineRemover)
+		if !ok {
+			continue
+		}
+		if err := remover.RemovePipeline(context.Background(), pipelineId); err != nil {
+			removePipelineErr = fmt.Errorf("actuator %v remove pipeline error: %v", key, err)
+		}
+	}
+
+	// RemovePipeline 失败时不记录 cleaned, 保证流水线下次还会被选中, 任务的 Remove 是可以重复调用的
+	if removePipelineErr != nil {
+		return removePipelineErr
+	}
+
+	if err := m.clientManager.taskClient.UpdateTaskCleaned(nil, cleanedIds); err != nil {
+		return err
+	}
+	return cleanErr
+}
+
+func removeJob(runner actuator.Actuator, job *actuator.Job) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	exist, err := runner.Exist(ctx, job)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return nil
+	}
+	return runner.Remove(ctx, job)
+}
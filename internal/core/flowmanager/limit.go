@@ -0,0 +1,179 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"eventops/apistructs"
+	"eventops/conf"
+	"eventops/internal/core/client/pipelineclient"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// runningUsage 记录运行中的流水线数量, 用于判断排队的流水线能否执行
+type runningUsage struct {
+	clientManager *clientManager
+
+	total  int
+	users  map[string]int
+	groups map[string]bool
+	quotas map[string]userQuota
+	// maxRunningPipelines 所有用户运行中的流水线数量上限, 0 代表不限制
+	maxRunningPipelines int
+}
+
+type userQuota struct {
+	maxRunningPipelines int
+	weight              int
+}
+
+func (m *FlowManager) getRunningUsage() (*runningUsage, error) {
+	runningPipelines, err := m.clientManager.pipelineClient.ListPipeline(nil, pipelineclient.ListPipelineQuery{
+		Statuses: []apistructs.PipelineStatus{apistructs.PipelineRunningStatus, apistructs.PipelinePausedStatus},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &runningUsage{
+		clientManager: m.clientManager,
+		users:         map[string]int{},
+		groups:        map[string]bool{},
+		quotas:        map[string]userQuota{},
+
+		maxRunningPipelines: conf.GetPipeline().Limit.MaxRunningPipelines,
+	}
+	for _, dbPipeline := range runningPipelines {
+		usage.add(&dbPipeline)
+	}
+	return usage, nil
+}
+
+func (u *runningUsage) add(dbPipeline *pipelineclient.Pipeline) {
+	if dbPipeline.ConcurrencyGroup != "" {
+		u.groups[dbPipeline.ConcurrencyGroup] = true
+	}
+	u.total++
+	u.users[dbPipeline.Creater]++
+}
+
+// waitReason 返回流水线需要继续排队的原因, 为空时可以执行
+func (u *runningUsage) waitReason(dbPipeline *pipelineclient.Pipeline) (string, error) {
+	if dbPipeline.ConcurrencyGroup != "" && u.groups[dbPipeline.ConcurrencyGroup] {
+		return fmt.Sprintf("concurrency group %v has running pipeline", dbPipeline.ConcurrencyGroup), nil
+	}
+
+	if u.maxRunningPipelines > 0 && u.total >= u.maxRunningPipelines {
+		return fmt.Sprintf("max running pipelines %v reached", u.maxRunningPipelines), nil
+	}
+
+	quota, err := u.getUserQuota(dbPipeline.Creater)
+	if err != nil {
+		return "", err
+	}
+	if quota.maxRunningPipelines > 0 && u.users[dbPipeline.Creater] >= quota.maxRunningPipelines {
+		return fmt.Sprintf("user %v max running pipelines %v reached", dbPipeline.Creater, quota.maxRunningPipelines), nil
+	}
+	return "", nil
+}
+
+// getUserQuota 用户表中设置了上限时使用用户的, 否则使用服务端的配置
+func (u *runningUsage) getUserQuota(user string) (userQuota, error) {
+	if quota, ok := u.quotas[user]; ok {
+		return quota, nil
+	}
+
+	quota := userQuota{
+		maxRunningPipelines: conf.GetPipeline().Limit.MaxRunningPipelinesPerUser,
+		weight:              1,
+	}
+	dbUser, find, err := u.clientManager.userClient.GetUserByName(nil, user)
+	if err != nil {
+		return quota, err
+	}
+	if find {
+		if dbUser.MaxRunningPipelines > 0 {
+			quota.maxRunningPipelines = dbUser.MaxRunningPipelines
+		}
+		if dbUser.ScheduleWeight > 1 {
+			quota.weight = dbUser.ScheduleWeight
+		}
+	}
+
+	u.quotas[user] = quota
+	return quota, nil
+}
+
+// actuatorLimiter 记录每个执行器上运行中的任务数量
+type actuatorLimiter struct {
+	lock    sync.Mutex
+	running map[string]int
+}
+
+func newActuatorLimiter() *actuatorLimiter {
+	return &actuatorLimiter{running: map[string]int{}}
+}
+
+// acquire force 为 true 时不检查上限, 用于恢复服务重启前已经在执行器上创建的任务
+func (l *actuatorLimiter) acquire(key string, limit int, force bool) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if !force && limit > 0 && l.running[key] >= limit {
+		return false
+	}
+	l.running[key]++
+	return true
+}
+
+func (l *actuatorLimiter) release(key string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.running[key]--
+	if l.running[key] <= 0 {
+		delete(l.running, key)
+	}
+}
+
+// waitActuatorSlot 执行器运行的任务达到上限时, 等待执行器空闲后再创建任务, 流水线结束时返回 false
+func (node *Node) waitActuatorSlot() bool {
+	limit := conf.GetPipeline().Limit.MaxRunningTasksPerActuator
+	if limit <= 0 {
+		return true
+	}
+
+	force := node.getTask().Status != apistructs.InitTaskStatus
+	for {
+		if node.flowManager.actuatorLimiter.acquire(node.actuatorKey, limit, force) {
+			node.holdActuatorSlot = true
+			return true
+		}
+
+		select {
+		case <-node.flow.getCtx().Done():
+			return false
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (node *Node) releaseActuatorSlot() {
+	if !node.holdActuatorSlot {
+		return
+	}
+	node.holdActuatorSlot = false
+	node.flowManager.actuatorLimiter.release(node.actuatorKey)
+}
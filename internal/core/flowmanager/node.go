@@ -21,6 +21,7 @@ import (
 	"eventops/internal/core/actuator"
 	"eventops/internal/core/client/pipelineclient"
 	"eventops/internal/core/client/taskclient"
+	"eventops/pkg/condition"
 	"eventops/pkg/dag"
 	"eventops/pkg/limit_sync_group"
 	"eventops/pkg/placeholder"
@@ -45,6 +46,18 @@ type Node struct {
 
 	runner actuator.Actuator
 	job    *actuator.Job
+
+	// logRunner logJob 是运行中任务的 job 副本, 读取日志的请求不能直接访问执行中修改的 runner 和 job
+	logLock   sync.Mutex
+	logRunner actuator.Actuator
+	logJob    *actuator.Job
+
+	// actuatorKey 用于统计执行器上运行中的任务数量
+	actuatorKey      string
+	holdActuatorSlot bool
+
+	approvalLock sync.Mutex
+	approvalChan chan struct{}
 }
 
 func NewNode(flow *Flow, parentTaskId uint64, taskDefinition *pipeline.Task, image string) *Node {
@@ -56,6 +69,8 @@ func NewNode(flow *Flow, parentTaskId uint64, taskDefinition *pipeline.Task, ima
 		parentTaskId:   parentTaskId,
 
 		image: image,
+
+		approvalChan: make(chan struct{}, 1),
 	}
 
 	dbTask := node.getTask()
@@ -69,16 +84,42 @@ func (node *Node) Run() error {
 	}
 
 	if node.getTask().Status.IsFailedStatus() {
+		if node.continueOnFailure() {
+			return nil
+		}
 		node.flow.lazyStopPipeline(apistructs.PipelineFailedStatus, fmt.Sprintf("node alias: %v parent_task_id: %v pipeline image: %v exec error %v",
 			node.getTask().Alias, node.getTask().ParentTaskId, node.image, node.getTask().Extra.Error))
 		return fmt.Errorf(node.getTask().Extra.Error)
 	}
 
-	var err error
-	if node.getTask().Type != apistructs.PipeType {
-		err = node.exec()
-	} else {
+	skip, err := node.checkWhen()
+	if err != nil {
+		taskUpdateError := node.setDbTask(WithStatus(apistructs.ErrorTaskStatus), WithExtraError(err.Error()))
+		if taskUpdateError != nil {
+			logrus.Errorf("task %v extra error: %v update failed: %v", node.getTask().Id, err, taskUpdateError)
+		}
+		if node.continueOnFailure() {
+			return nil
+		}
+
+		node.flow.lazyStopPipeline(apistructs.PipelineFailedStatus, fmt.Sprintf("node alias: %v parent_task_id: %v pipeline image: %v when error: %v",
+			node.getTask().Alias, node.getTask().ParentTaskId, node.image, err))
+		return err
+	}
+	if skip {
+		node.runNextNodes()
+		return nil
+	}
+
+	switch {
+	case node.getTask().Type == apistructs.PipeType:
 		err = node.execPipelineTypeTask()
+	case node.getTask().Type == apistructs.ApprovalType:
+		err = node.execApprovalTask()
+	case node.taskDefinition.Matrix != nil:
+		err = node.execMatrixTask()
+	default:
+		err = node.exec()
 	}
 	if err == nil {
 		err = node.setContext()
@@ -88,16 +129,46 @@ func (node *Node) Run() error {
 		if taskUpdateError != nil {
 			logrus.Errorf("task %v extra error: %v update failed: %v", node.getTask().Id, err, taskUpdateError)
 		}
+		if node.continueOnFailure() {
+			return nil
+		}
 
 		node.flow.lazyStopPipeline(apistructs.PipelineFailedStatus, fmt.Sprintf("node alias: %v parent_task_id: %v pipeline image: %v exec error: %v",
 			node.getTask().Alias, node.getTask().ParentTaskId, node.image, err))
 		return err
 	}
 
+	// 任务执行失败或审批被拒绝, 下游任务不再执行
+	if node.getTask().Status.IsFailedStatus() {
+		if node.continueOnFailure() {
+			return nil
+		}
+		node.flow.lazyStopPipeline(apistructs.PipelineFailedStatus, fmt.Sprintf("node alias: %v parent_task_id: %v pipeline image: %v status: %v",
+			node.getTask().Alias, node.getTask().ParentTaskId, node.image, node.getTask().Status))
+		return fmt.Errorf("task %v status %v", node.getTask().Alias, node.getTask().Status)
+	}
+
 	node.runNextNodes()
 	return nil
 }
 
+// continueOnFailure allowFailure 的任务失败后记录警告并继续执行下游任务, 被取消的任务不会继续
+func (node *Node) continueOnFailure() bool {
+	if !node.taskDefinition.AllowFailure || node.flow.getCtx().Err() != nil {
+		return false
+	}
+
+	task := node.getTask()
+	if task.Status == apistructs.CancelTaskStatus {
+		return false
+	}
+
+	node.flow.addWarning(fmt.Sprintf("node alias: %v parent_task_id: %v pipeline image: %v status: %v %v",
+		task.Alias, task.ParentTaskId, node.image, task.Status, task.Extra.Error))
+	node.runNextNodes()
+	return true
+}
+
 func (node *Node) execPipelineTypeTask() error {
 	if node.getTask().Status.IsDoneStatus() {
 		return nil
@@ -147,16 +218,21 @@ func (node *Node) execPipelineTypeTask() error {
 	}
 
 	var allTaskIsSuccessStatus = true
-	for _, task := range definition.Tasks {
-		task := node.flow.getTask(node.getTask().Id, task.Alias)
+	for _, taskDefinition := range definition.Tasks {
+		task := node.flow.getTask(node.getTask().Id, taskDefinition.Alias)
 		if task == nil {
 			allTaskIsSuccessStatus = false
 			break
 		}
-		if task.Status != apistructs.SuccessTaskStatus {
-			allTaskIsSuccessStatus = false
-			break
+		if task.Status == apistructs.SuccessTaskStatus || task.Status == apistructs.SkippedTaskStatus {
+			continue
+		}
+		// allowFailure 的任务失败不影响所在流水线的结果
+		if taskDefinition.AllowFailure && task.Status.IsFailedStatus() && task.Status != apistructs.CancelTaskStatus {
+			continue
 		}
+		allTaskIsSuccessStatus = false
+		break
 	}
 
 	if allTaskIsSuccessStatus {
@@ -169,6 +245,35 @@ func (node *Node) execPipelineTypeTask() error {
 	}
 }
 
+// 校验 when 条件, 条件不满足的任务标记为 skipped, 下游任务视其为已完成
+func (node *Node) checkWhen() (bool, error) {
+	if node.getTask().Status == apistructs.SkippedTaskStatus {
+		return true, nil
+	}
+
+	// matrix 任务的 when 在每个实例上分别校验
+	if node.taskDefinition.When == "" || node.taskDefinition.Matrix != nil || node.getTask().Status != apistructs.InitTaskStatus {
+		return false, nil
+	}
+
+	replaceValue, err := node.buildReplaceValue()
+	if err != nil {
+		return false, err
+	}
+
+	pass, err := condition.Eval(node.taskDefinition.When, func(holder string) string {
+		return placeholder.ReplacePlaceholder(holder, replaceValue, false)
+	})
+	if err != nil {
+		return false, err
+	}
+	if pass {
+		return false, nil
+	}
+
+	return true, node.setDbTask(WithStatus(apistructs.SkippedTaskStatus))
+}
+
 func (node *Node) runNextNodes() {
 	definition, err := node.flow.getAndSetPipelineVersionDefinition(node.image)
 	if err != nil {
@@ -185,6 +290,11 @@ func (node *Node) runNextNodes() {
 		wait.Add(1)
 		go func(index int) {
 			defer wait.Done()
+			// 流水线暂停时不再创建新的任务
+			if !node.flow.waitResume() {
+				return
+			}
+
 			parentTaskId := node.getTask().ParentTaskId
 			taskDefinition, err := node.createNextRunDbTask(parentTaskId, nextNodes[index], node.image)
 			if err != nil {
@@ -216,31 +326,41 @@ func (node *Node) createNextRunDbTask(parentTaskId uint64, dagNode pipeline.Node
 		return nil, fmt.Errorf("not find task alias: %v definition in pipeline image: %v", dagNode.Name, image)
 	}
 
-	if node.flow.getTask(parentTaskId, dagNode.Name) == nil {
-		var extra = taskclient.TaskExtra{
-			Auth: getRandomString(32),
-		}
-		dbTask := &taskclient.Task{
-			PipelineId:   node.flow.getPipe().Id,
-			Alias:        dagNode.Name,
-			Type:         taskDefinition.Type,
-			Status:       apistructs.InitTaskStatus,
-			Extra:        &extra,
-			Outputs:      &taskclient.Outputs{},
-			CostTimeSec:  0,
-			Creater:      node.flow.getPipe().Creater,
-			ParentTaskId: parentTaskId,
-		}
+	if err := node.createDbTask(parentTaskId, dagNode.Name, taskDefinition.Type, nil); err != nil {
+		return nil, err
+	}
 
-		_, err := node.flowManager.clientManager.taskClient.CreateTask(nil, dbTask)
-		if err != nil {
-			return nil, err
-		}
+	return taskDefinition, nil
+}
 
-		node.flow.addTask(dbTask)
+func (node *Node) createDbTask(parentTaskId uint64, alias string, taskType apistructs.TaskType, matrix map[string]string) error {
+	if node.flow.getTask(parentTaskId, alias) != nil {
+		return nil
 	}
 
-	return taskDefinition, nil
+	var extra = taskclient.TaskExtra{
+		Auth:   getRandomString(32),
+		Matrix: matrix,
+	}
+	dbTask := &taskclient.Task{
+		PipelineId:   node.flow.getPipe().Id,
+		Alias:        alias,
+		Type:         taskType,
+		Status:       apistructs.InitTaskStatus,
+		Extra:        &extra,
+		Outputs:      &taskclient.Outputs{},
+		CostTimeSec:  0,
+		Creater:      node.flow.getPipe().Creater,
+		ParentTaskId: parentTaskId,
+	}
+
+	_, err := node.flowManager.clientManager.taskClient.CreateTask(nil, dbTask)
+	if err != nil {
+		return err
+	}
+
+	node.flow.addTask(dbTask)
+	return nil
 }
 
 func getRandomString(n int) string {
@@ -269,6 +389,14 @@ func (node *Node) buildReplaceValue() (*placeholder.ReplaceValue, error) {
 	}
 	replaceValue.Outputs = outputs
 
+	tasks, err := node.getPlaceholderTaskValue()
+	if err != nil {
+		return nil, err
+	}
+	replaceValue.Tasks = tasks
+	replaceValue.Pipeline = map[string]string{pipeline.PipelineStatusKey: string(node.flow.getStopStatus())}
+	replaceValue.Matrix = node.getTask().Extra.Matrix
+
 	replaceValue.TaskId = node.getTask().Id
 	replaceValue.PipelineId = node.flow.getPipe().Id
 
@@ -451,6 +579,86 @@ func (node *Node) allNeedNodeIsDone(dagNode pipeline.Node) bool {
 }
 
 func (node *Node) exec() error {
+	for {
+		err := node.execJob()
+		node.archiveLogs()
+		if node.flow.getCtx().Err() != nil {
+			return err
+		}
+
+		var status = node.getTask().Status
+		var errMsg = node.getTask().Extra.Error
+		if err != nil {
+			status = apistructs.ErrorTaskStatus
+			errMsg = err.Error()
+		}
+		if !node.canRetry(status) {
+			return err
+		}
+
+		if err := node.retry(status, errMsg); err != nil {
+			return err
+		}
+	}
+}
+
+func (node *Node) canRetry(status apistructs.TaskStatus) bool {
+	retryDefinition := node.taskDefinition.Retry
+	if retryDefinition == nil {
+		return false
+	}
+
+	if !status.IsFailedStatus() || !retryDefinition.IsRetryable(status) {
+		return false
+	}
+
+	return len(node.getTask().Extra.Attempts)+1 < retryDefinition.MaxAttempts
+}
+
+func (node *Node) retry(status apistructs.TaskStatus, errMsg string) error {
+	attempt := apistructs.TaskAttempt{
+		Attempt:   len(node.getTask().Extra.Attempts) + 1,
+		Status:    status,
+		Error:     errMsg,
+		JobSign:   node.getTask().JobSign,
+		TimeBegin: node.attemptTimeBegin(),
+		TimeEnd:   &[]time.Time{time.Now()}[0],
+	}
+
+	// 删除上一次执行的 job, 避免重新创建的时候冲突
+	if node.runner != nil && node.job != nil && node.job.JobSign != "" {
+		if err := node.runner.Remove(context.Background(), node.job); err != nil {
+			logrus.Warnf("task %v attempt %v remove job %v error: %v", node.getTask().Id, attempt.Attempt, node.job.JobSign, err)
+		}
+	}
+	node.job = nil
+	node.setLogJob(nil, nil)
+
+	if err := node.setDbTask(WithAttempt(attempt)); err != nil {
+		return err
+	}
+
+	select {
+	case <-node.flow.getCtx().Done():
+		return nil
+	case <-time.After(node.taskDefinition.Retry.GetInterval(attempt.Attempt)):
+	}
+	return nil
+}
+
+// 超时时间从本次执行的 job 创建开始计算, 没有记录时(job 还没有创建)使用上一次执行的结束时间
+func (node *Node) attemptTimeBegin() *time.Time {
+	if node.getTask().Extra.AttemptTimeBegin != nil {
+		return node.getTask().Extra.AttemptTimeBegin
+	}
+	attempts := node.getTask().Extra.Attempts
+	if len(attempts) > 0 {
+		return attempts[len(attempts)-1].TimeEnd
+	}
+	return node.getTask().TimeBegin
+}
+
+func (node *Node) execJob() error {
 	if node.getTask().Status.IsDoneStatus() {
 		return nil
 	}
@@ -472,16 +680,22 @@ func (node *Node) exec() error {
 		node.setTask(WithExtraTag(chooseTag))
 	}
 
+	if !node.waitActuatorSlot() {
+		return node.setDbTask(WithStatus(apistructs.CancelTaskStatus))
+	}
+	defer node.releaseActuatorSlot()
+
+	ctx := node.flow.getCtx()
 	var waitTime = 1
 	switch node.getTask().Status {
 	case apistructs.InitTaskStatus:
 		err := node.flowManager.clientManager.db.Transaction(func(tx *gorm.DB) error {
-			createJob, err := node.runner.Create(node.flow.ctx, node.job)
+			createJob, err := node.runner.Create(ctx, node.job)
 			if err != nil {
 				return err
 			}
 
-			return node.setDbTask(WithStatus(apistructs.CreatedTaskStatus), WithJobSign(createJob.JobSign))
+			return node.setDbTask(WithStatus(apistructs.CreatedTaskStatus), WithJobSign(createJob.JobSign), WithAttemptTimeBegin(time.Now()))
 		})
 		if err != nil {
 			return err
@@ -489,7 +703,7 @@ func (node *Node) exec() error {
 		fallthrough
 	case apistructs.CreatedTaskStatus:
 		err := node.flowManager.clientManager.db.Transaction(func(tx *gorm.DB) error {
-			err := node.runner.Start(node.flow.ctx, node.job)
+			err := node.runner.Start(ctx, node.job)
 			if err != nil {
 				return err
 			}
@@ -501,9 +715,10 @@ func (node *Node) exec() error {
 		}
 		fallthrough
 	case apistructs.RunningTaskStatus:
+		node.setLogJob(node.runner, node.job)
 		for {
 			select {
-			case <-node.flow.ctx.Done():
+			case <-ctx.Done():
 				return node.flowManager.clientManager.db.Transaction(func(tx *gorm.DB) error {
 					err := node.setDbTask(WithStatus(apistructs.CancelTaskStatus))
 					if err != nil {
@@ -517,7 +732,7 @@ func (node *Node) exec() error {
 					waitTime = waitTime + 2
 				}
 
-				value := int64(time.Now().Sub(*node.getTask().TimeBegin) / time.Second)
+				value := int64(time.Now().Sub(*node.attemptTimeBegin()) / time.Second)
 				if value > node.taskDefinition.Timeout {
 					return node.flowManager.clientManager.db.Transaction(func(tx *gorm.DB) error {
 						err := node.setDbTask(WithStatus(apistructs.TimeoutTaskStatus))
@@ -529,7 +744,7 @@ func (node *Node) exec() error {
 					})
 				}
 
-				status, err := node.runner.Status(node.flow.ctx, node.job)
+				status, err := node.runner.Status(ctx, node.job)
 				if err != nil {
 					return err
 				}
@@ -578,6 +793,7 @@ func (node *Node) buildActuatorJob() (*actuator.Job, error) {
 		TaskId:         strconv.FormatUint(node.getTask().Id, 10),
 		DefinitionTask: node.taskDefinition,
 		JobSign:        node.getTask().JobSign,
+		Attempt:        len(node.getTask().Extra.Attempts),
 	}
 
 	replaceValue, err := node.buildReplaceValue()
@@ -621,6 +837,10 @@ func (node *Node) buildActuatorJob() (*actuator.Job, error) {
 		preCommands = append(preCommands, fmt.Sprintf("mc cp %v %v", minioPath, localPath))
 	}
 
+	// 缓存的恢复放在文件下载之后, 用户命令之前
+	cacheRestoreCommands, cacheSaveCommands := node.buildCacheCommands(replaceValue, minioAlias)
+	preCommands = append(preCommands, cacheRestoreCommands...)
+
 	// 如果存在下载命令，则在最前面构建 mc alias 命令
 	var minioAliasServerCommand = fmt.Sprintf("mc alias set %v %v %v %v", minioAlias, conf.GetMinio().Server, conf.GetMinio().AccessKeyId, conf.GetMinio().SecretAccessKey)
 	if len(preCommands) > 0 {
@@ -641,6 +861,9 @@ func (node *Node) buildActuatorJob() (*actuator.Job, error) {
 		}
 	}
 
+	// 用户命令执行成功后保存缓存
+	nextCommands = append(nextCommands, cacheSaveCommands...)
+
 	// 如果存在上传命令，则在最前面构建 mc alias 命令
 	if len(nextCommands) > 0 {
 		nextCommands = append([]string{minioAliasServerCommand}, nextCommands...)
@@ -14,6 +14,8 @@
 package flowmanager
 
 import (
+	"errors"
+	"eventops/apistructs"
 	"eventops/internal/core/actuator"
 	"eventops/internal/core/actuator/docker"
 	"eventops/internal/core/actuator/k8s"
@@ -39,7 +41,7 @@ func (node *Node) actuator() (actuator.Actuator, string, error) {
 	var allTags []string
 	allTags = append(allTags, taskTags...)
 	allTags = append(allTags, pipelineTags...)
-	actuatorDefinitionMap, err := node.getTagsActuatorDefinitionMap(allTags)
+	actuatorDefinitionMap, err := node.flowManager.getTagsActuatorDefinitionMap(node.getTask().Creater, node.getTask().Type, allTags)
 	if err != nil {
 		return nil, "", err
 	}
@@ -82,31 +84,36 @@ func (node *Node) actuator() (actuator.Actuator, string, error) {
 		return nil, "", fmt.Errorf("task alias: %v type: %v No suitable actuatordefinition found", node.getTask().Alias, node.getTask().Type)
 	}
 
+	newActuator, err := node.flowManager.newActuator(node.getTask().Creater, chooseTag, *chooseActuatorDefinition)
+	if err != nil {
+		return nil, "", err
+	}
+	node.actuatorKey = fmt.Sprintf("%v/%v", node.getTask().Creater, chooseActuatorDefinition.Name)
+	return newActuator, chooseTag, nil
+}
+
+func (m *FlowManager) newActuator(creater string, tag string, definition actuatordefinition.Client) (actuator.Actuator, error) {
 	var dialer remotedialer.Dialer
-	if chooseActuatorDefinition.Tunnel != nil {
+	if definition.Tunnel != nil {
 		err := retry.DoWithInterval(func() error {
-			dialer = node.flowManager.dialerServer.GetClient(node.getTask().Creater, chooseActuatorDefinition.Tunnel.ClientId)
+			dialer = m.dialerServer.GetClient(creater, definition.Tunnel.ClientId)
 			if dialer == nil {
 				return fmt.Errorf("not find dialer client")
 			}
 			return nil
 		}, 10, 5*time.Second)
 		if err != nil {
-			return nil, "", fmt.Errorf("not find tag: %v actuator name: %v tunnel client", chooseTag, chooseActuatorDefinition.Name)
+			return nil, fmt.Errorf("not find tag: %v actuator name: %v tunnel client", tag, definition.Name)
 		}
 	}
 
-	newActuator, err := NewActuator(*chooseActuatorDefinition, dialer)
-	if err != nil {
-		return nil, "", err
-	}
-	return newActuator, chooseTag, nil
+	return NewActuator(definition, dialer)
 }
 
-func (node *Node) getTagsActuatorDefinitionMap(tags []string) (map[string][]actuatordefinition.Client, error) {
-	actuatorTag, err := node.flowManager.clientManager.actuatorClient.ListActuatorTags(nil, actuatorclient.ListActuatorTagQuery{
+func (m *FlowManager) getTagsActuatorDefinitionMap(creater string, taskType apistructs.TaskType, tags []string) (map[string][]actuatordefinition.Client, error) {
+	actuatorTag, err := m.clientManager.actuatorClient.ListActuatorTags(nil, actuatorclient.ListActuatorTagQuery{
 		Tags:            tags,
-		ActuatorCreater: node.getTask().Creater,
+		ActuatorCreater: creater,
 	})
 	if err != nil {
 		return nil, err
@@ -120,7 +127,7 @@ func (node *Node) getTagsActuatorDefinitionMap(tags []string) (map[string][]actu
 		actuatorIdList = append(actuatorIdList, tag.ActuatorId)
 	}
 
-	dbActuators, err := node.flowManager.clientManager.actuatorClient.ListActuator(nil, actuatorclient.ListActuatorQuery{
+	dbActuators, err := m.clientManager.actuatorClient.ListActuator(nil, actuatorclient.ListActuatorQuery{
 		IdList: actuatorIdList,
 	})
 	if err != nil {
@@ -134,7 +141,7 @@ func (node *Node) getTagsActuatorDefinitionMap(tags []string) (map[string][]actu
 		if err != nil {
 			return nil, err
 		}
-		if actuatorInfo.GetType() != node.getTask().Type {
+		if actuatorInfo.GetType() != taskType {
 			continue
 		}
 
@@ -158,3 +165,19 @@ func NewActuator(client actuatordefinition.Client, dialer remotedialer.Dialer) (
 	}
 	return nil, fmt.Errorf("not support actuator type")
 }
+
+// actuatorNotFindError tag 对应的执行器定义已经不存在
+var actuatorNotFindError = errors.New("No suitable actuatordefinition found")
+
+func (m *FlowManager) getTagActuator(creater string, taskType apistructs.TaskType, tag string) (actuator.Actuator, error) {
+	actuatorDefinitionMap, err := m.getTagsActuatorDefinitionMap(creater, taskType, []string{tag})
+	if err != nil {
+		return nil, err
+	}
+
+	list := actuatorDefinitionMap[tag]
+	if len(list) == 0 {
+		return nil, fmt.Errorf("type: %v tag: %v %w", taskType, tag, actuatorNotFindError)
+	}
+	return m.newActuator(creater, tag, list[0])
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"eventops/apistructs"
+	"eventops/pkg/placeholder"
+	"fmt"
+	"time"
+)
+
+// execApprovalTask 审批任务不在执行器上运行, 进入 waitApproval 状态后等待用户审批, 超时视为拒绝
+func (node *Node) execApprovalTask() error {
+	if node.getTask().Status.IsDoneStatus() {
+		return nil
+	}
+
+	if node.getTask().Status == apistructs.InitTaskStatus {
+		var approval = &apistructs.TaskApproval{}
+		if node.taskDefinition.Approval != nil {
+			replaceValue, err := node.buildReplaceValue()
+			if err != nil {
+				return err
+			}
+			approval.Approvers = node.taskDefinition.Approval.Approvers
+			approval.Message = placeholder.ReplacePlaceholder(node.taskDefinition.Approval.Message, replaceValue, false)
+		}
+		if err := node.setDbTask(WithStatus(apistructs.WaitApprovalTaskStatus), WithApproval(approval)); err != nil {
+			return err
+		}
+	}
+
+	// 服务重启后恢复的审批任务从第一次进入等待的时间开始计算超时
+	timeout := time.Duration(node.taskDefinition.Timeout)*time.Second - time.Since(*node.getTask().TimeBegin)
+	if timeout < 0 {
+		timeout = 0
+	}
+
+	select {
+	case <-node.flow.getCtx().Done():
+		node.approvalLock.Lock()
+		defer node.approvalLock.Unlock()
+		if node.getTask().Status.IsDoneStatus() {
+			return nil
+		}
+		return node.setDbTask(WithStatus(apistructs.CancelTaskStatus))
+	case <-node.approvalChan:
+		return nil
+	case <-time.After(timeout):
+		node.approvalLock.Lock()
+		defer node.approvalLock.Unlock()
+		if node.getTask().Status.IsDoneStatus() {
+			return nil
+		}
+		return node.setDbTask(WithStatus(apistructs.RejectedTaskStatus), WithApproval(node.buildApprovalResult(apistructs.RejectedResult, "", "approval timeout")))
+	}
+}
+
+func (node *Node) approve(user string, approved bool, comment string) error {
+	node.approvalLock.Lock()
+	defer node.approvalLock.Unlock()
+
+	task := node.getTask()
+	if task.Status != apistructs.WaitApprovalTaskStatus {
+		return fmt.Errorf("task %v status %v not %v", task.Id, task.Status, apistructs.WaitApprovalTaskStatus)
+	}
+
+	if !node.isApprover(user) {
+		return fmt.Errorf("user %v not task %v approver", user, task.Id)
+	}
+
+	var status = apistructs.SuccessTaskStatus
+	var result = apistructs.ApprovedResult
+	if !approved {
+		status = apistructs.RejectedTaskStatus
+		result = apistructs.RejectedResult
+	}
+	if err := node.setDbTask(WithStatus(status), WithApproval(node.buildApprovalResult(result, user, comment))); err != nil {
+		return err
+	}
+
+	select {
+	case node.approvalChan <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (node *Node) isApprover(user string) bool {
+	var approvers []string
+	if approval := node.getTask().Extra.Approval; approval != nil {
+		approvers = approval.Approvers
+	}
+	if len(approvers) == 0 {
+		approvers = []string{node.flow.getPipe().Creater}
+	}
+
+	for _, approver := range approvers {
+		if approver == user {
+			return true
+		}
+	}
+	return false
+}
+
+func (node *Node) buildApprovalResult(result apistructs.ApprovalResult, user string, comment string) *apistructs.TaskApproval {
+	var approval = apistructs.TaskApproval{}
+	if node.getTask().Extra.Approval != nil {
+		approval = *node.getTask().Extra.Approval
+	}
+	approval.Result = result
+	approval.Operator = user
+	approval.Comment = comment
+	approval.Time = &[]time.Time{time.Now()}[0]
+	return &approval
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"eventops/pkg/placeholder"
+	"eventops/pkg/schema/pipeline"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var cacheKeyRe = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+func buildMinioCachePath(creater string, pipelineName string) string {
+	return fmt.Sprintf("caches/%v/%v", creater, pipelineName)
+}
+
+// buildCacheCommands 构建缓存的恢复和保存命令
+// 恢复命令在用户命令之前执行, 缓存不存在不影响任务执行
+// 保存命令在用户命令执行成功后执行, 同一个 key 的缓存已经存在则不再上传
+func (node *Node) buildCacheCommands(replaceValue *placeholder.ReplaceValue, minioAlias string) (restoreCommands []string, saveCommands []string) {
+	for index, cache := range node.taskDefinition.Caches {
+		keyEnv := fmt.Sprintf("EVENTOPS_CACHE_KEY_%v", index)
+		key := cacheKeyRe.ReplaceAllString(placeholder.ReplacePlaceholder(cache.Key, replaceValue, false), "-")
+
+		var keyValue = key
+		if len(cache.HashFiles) > 0 {
+			var hashFiles []string
+			for _, file := range cache.HashFiles {
+				hashFiles = append(hashFiles, placeholder.ReplacePlaceholder(file, replaceValue, true))
+			}
+			keyValue = fmt.Sprintf("%v-$(cat %v 2>/dev/null | sha256sum | cut -c1-16)", key, strings.Join(hashFiles, " "))
+		}
+
+		var paths []string
+		for _, path := range cache.Paths {
+			path = placeholder.ReplacePlaceholder(path, replaceValue, true)
+			paths = append(paths, strings.TrimPrefix(path, "/"))
+		}
+
+		minioPath := buildMinioPath(minioAlias, fmt.Sprintf("%v/${%v}.tar.gz", buildMinioCachePath(node.getTask().Creater, pipeline.GetImageName(node.image)), keyEnv))
+		localPath := fmt.Sprintf("/tmp/eventops-cache-%v-%v.tar.gz", node.getTask().Id, index)
+
+		restoreCommands = append(restoreCommands, fmt.Sprintf("export %v=\"%v\"", keyEnv, keyValue))
+		restoreCommands = append(restoreCommands, fmt.Sprintf("(mc cp %v %v > /dev/null 2>&1 && tar -xzf %v -C / && rm -f %v && echo \"cache ${%v} restored\" || echo \"cache ${%v} not found\")",
+			minioPath, localPath, localPath, localPath, keyEnv, keyEnv))
+
+		saveCommands = append(saveCommands, fmt.Sprintf("(mc stat %v > /dev/null 2>&1 && echo \"cache ${%v} already exists\" || (cd / && tar -czf %v %v && mc cp %v %v && rm -f %v && echo \"cache ${%v} saved\") || echo \"cache ${%v} save failed\")",
+			minioPath, keyEnv, localPath, strings.Join(paths, " "), localPath, minioPath, localPath, keyEnv, keyEnv))
+	}
+	return restoreCommands, saveCommands
+}
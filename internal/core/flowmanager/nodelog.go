@@ -0,0 +1,190 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"bytes"
+	"context"
+	"eventops/apistructs"
+	"eventops/conf"
+	"eventops/internal/core/actuator"
+	"eventops/internal/core/client/taskclient"
+	"eventops/internal/core/client/tasklogclient"
+	"eventops/pkg/schema/pipeline"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 归档的日志保存在 mediumtext 中, 最多 16M-1 字节
+const (
+	defaultArchiveLogSize = 4 << 20
+	maxArchiveLogSize     = 16<<20 - 1
+)
+
+// archiveLogMaxSize 没有配置时使用默认值, 超过数据库字段长度时使用字段长度
+func archiveLogMaxSize(size int64) int64 {
+	if size <= 0 {
+		return defaultArchiveLogSize
+	}
+	if size > maxArchiveLogSize {
+		return maxArchiveLogSize
+	}
+	return size
+}
+
+func (node *Node) setLogJob(runner actuator.Actuator, job *actuator.Job) {
+	node.logLock.Lock()
+	defer node.logLock.Unlock()
+
+	node.logRunner = runner
+	node.logJob = nil
+	if job != nil {
+		copyJob := *job
+		node.logJob = &copyJob
+	}
+}
+
+func (node *Node) getLogJob() (actuator.Actuator, *actuator.Job) {
+	node.logLock.Lock()
+	defer node.logLock.Unlock()
+
+	return node.logRunner, node.logJob
+}
+
+// archiveLogs 任务结束后把执行器上的日志保存到数据库, 执行器上的资源回收后仍然可以查看
+func (node *Node) archiveLogs() {
+	if !conf.GetPipeline().Log.Archive {
+		return
+	}
+
+	runner, job := node.runner, node.job
+	if runner == nil || job == nil || job.JobSign == "" {
+		return
+	}
+
+	task := node.getTask()
+	if !task.Status.IsDoneStatus() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	reader, err := runner.Logs(ctx, job, false)
+	if err != nil {
+		logrus.Warnf("task %v get logs error: %v", task.Id, err)
+		return
+	}
+	defer reader.Close()
+
+	content, truncated, err := readTailLogs(reader, archiveLogMaxSize(conf.GetPipeline().Log.ArchiveMaxSize))
+	if err != nil {
+		logrus.Warnf("task %v read logs error: %v", task.Id, err)
+		return
+	}
+
+	_, err = node.flowManager.clientManager.taskLogClient.CreateTaskLog(nil, &tasklogclient.TaskLog{
+		PipelineId: task.PipelineId,
+		TaskId:     task.Id,
+		Attempt:    len(task.Extra.Attempts),
+		Content:    content,
+		Truncated:  truncated,
+		Creater:    task.Creater,
+	})
+	if err != nil {
+		logrus.Warnf("task %v archive logs error: %v", task.Id, err)
+	}
+}
+
+// readTailLogs 只保留最后 maxSize 字节的日志
+func readTailLogs(reader io.Reader, maxSize int64) (string, bool, error) {
+	var buffer bytes.Buffer
+	var truncated = false
+	var chunk = make([]byte, 32*1024)
+	for {
+		n, err := reader.Read(chunk)
+		buffer.Write(chunk[:n])
+		if maxSize > 0 && int64(buffer.Len()) > 2*maxSize {
+			buffer.Next(buffer.Len() - int(maxSize))
+			truncated = true
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	content := buffer.Bytes()
+	if maxSize > 0 && int64(len(content)) > maxSize {
+		content = content[int64(len(content))-maxSize:]
+		truncated = true
+	}
+	// 截断的位置可能在一个字符的中间
+	for truncated && len(content) > 0 && !utf8.RuneStart(content[0]) {
+		content = content[1:]
+	}
+	return strings.ToValidUTF8(string(content), ""), truncated, nil
+}
+
+// TaskLogs 运行中的任务直接从执行器读取日志, 结束的任务优先读取归档的日志
+func (m *FlowManager) TaskLogs(ctx context.Context, dbTask *taskclient.Task, follow bool) (io.ReadCloser, error) {
+	if dbTask.Type == apistructs.PipeType || dbTask.Type == apistructs.ApprovalType {
+		return nil, fmt.Errorf("[%v] task type not have logs", dbTask.Type)
+	}
+
+	if !dbTask.Status.IsDoneStatus() {
+		if flow := m.GetFlow(dbTask.PipelineId); flow != nil {
+			if node := flow.getNode(dbTask.Id); node != nil {
+				runner, job := node.getLogJob()
+				if runner != nil && job != nil && job.JobSign != "" {
+					return runner.Logs(ctx, job, follow)
+				}
+			}
+		}
+		return nil, fmt.Errorf("task %v status %v not start", dbTask.Id, dbTask.Status)
+	}
+
+	taskLog, find, err := m.clientManager.taskLogClient.GetTaskLog(nil, dbTask.Id, -1, dbTask.Creater)
+	if err != nil {
+		return nil, err
+	}
+	if find {
+		return ioutil.NopCloser(strings.NewReader(taskLog.Content)), nil
+	}
+
+	if dbTask.Cleaned || dbTask.JobSign == "" || dbTask.Extra == nil || dbTask.Extra.ChooseTag == "" {
+		return nil, fmt.Errorf("not find task %v logs", dbTask.Id)
+	}
+
+	runner, err := m.getTagActuator(dbTask.Creater, dbTask.Type, dbTask.Extra.ChooseTag)
+	if err != nil {
+		return nil, err
+	}
+	job := &actuator.Job{
+		PipelineId:     strconv.FormatUint(dbTask.PipelineId, 10),
+		TaskId:         strconv.FormatUint(dbTask.Id, 10),
+		DefinitionTask: &pipeline.Task{Alias: dbTask.Alias, Type: dbTask.Type},
+		JobSign:        dbTask.JobSign,
+		Attempt:        len(dbTask.Extra.Attempts),
+	}
+	return runner.Logs(ctx, job, false)
+}
@@ -0,0 +1,55 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestArchiveLogMaxSize(t *testing.T) {
+	var testData = []struct {
+		size int64
+		want int64
+	}{
+		{0, defaultArchiveLogSize},
+		{-1, defaultArchiveLogSize},
+		{1024, 1024},
+		{maxArchiveLogSize, maxArchiveLogSize},
+		{64 << 20, maxArchiveLogSize},
+	}
+	for _, data := range testData {
+		if got := archiveLogMaxSize(data.size); got != data.want {
+			t.Fatalf("archive log max size %v got %v, want %v", data.size, got, data.want)
+		}
+	}
+}
+
+func TestReadTailLogs(t *testing.T) {
+	content, truncated, err := readTailLogs(strings.NewReader("hello"), 10)
+	if err != nil || truncated || content != "hello" {
+		t.Fatalf("content %v truncated %v error %v", content, truncated, err)
+	}
+
+	content, truncated, err = readTailLogs(strings.NewReader(strings.Repeat("a", 100)+"tail"), 4)
+	if err != nil || !truncated || content != "tail" {
+		t.Fatalf("content %v truncated %v error %v", content, truncated, err)
+	}
+
+	// 截断在多字节字符中间时丢弃不完整的字符
+	content, truncated, err = readTailLogs(strings.NewReader("日志"), 4)
+	if err != nil || !truncated || content != "志" {
+		t.Fatalf("content %v truncated %v error %v", content, truncated, err)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"encoding/json"
+	"eventops/apistructs"
+	"eventops/internal/core/client/taskclient"
+	"eventops/pkg/limit_sync_group"
+	"eventops/pkg/placeholder"
+	"eventops/pkg/schema/pipeline"
+)
+
+type matrixInstance struct {
+	definition *pipeline.Task
+	values     map[string]string
+}
+
+// execMatrixTask matrix 任务按照 matrix 的取值展开成多个实例任务, 所有实例结束后把实例的出参汇总成 json 数组
+func (node *Node) execMatrixTask() error {
+	if node.getTask().Status.IsDoneStatus() {
+		return nil
+	}
+
+	instances, err := node.buildMatrixInstances()
+	if err != nil {
+		return err
+	}
+	if len(instances) == 0 {
+		return node.setDbTask(WithStatus(apistructs.SkippedTaskStatus))
+	}
+
+	if err := node.setDbTask(WithStatus(apistructs.RunningTaskStatus)); err != nil {
+		return err
+	}
+
+	var parallel = node.taskDefinition.Matrix.MaxParallel
+	if parallel <= 0 || parallel > len(instances) {
+		parallel = len(instances)
+	}
+	worker := limit_sync_group.NewWorker(parallel)
+	for index := range instances {
+		worker.AddFunc(func(locker *limit_sync_group.Locker, i ...interface{}) error {
+			if err := node.flow.getCtx().Err(); err != nil {
+				return err
+			}
+
+			instance := instances[i[0].(int)]
+			err := node.createDbTask(node.parentTaskId, instance.definition.Alias, instance.definition.Type, instance.values)
+			if err != nil {
+				node.flow.lazyStopPipeline(apistructs.PipelineFailedStatus, err.Error())
+				return err
+			}
+
+			instanceNode := NewNode(node.flow, node.parentTaskId, instance.definition, node.image)
+			return instanceNode.Run()
+		}, index)
+	}
+	_ = worker.Do().Error()
+
+	var allSuccess = true
+	var allSkipped = true
+	var anyFailed = false
+	var outputValues = map[string][]string{}
+	for _, instance := range instances {
+		task := node.flow.getTask(node.parentTaskId, instance.definition.Alias)
+		if task == nil {
+			allSuccess = false
+			allSkipped = false
+			continue
+		}
+		if task.Status != apistructs.SkippedTaskStatus {
+			allSkipped = false
+		}
+		if task.Status != apistructs.SuccessTaskStatus && task.Status != apistructs.SkippedTaskStatus {
+			allSuccess = false
+		}
+		if task.Status.IsFailedStatus() && task.Status != apistructs.CancelTaskStatus {
+			anyFailed = true
+		}
+
+		for _, output := range node.taskDefinition.Outputs {
+			var value string
+			if task.Outputs != nil {
+				value = (*task.Outputs)[output.Name].Value
+			}
+			outputValues[output.Name] = append(outputValues[output.Name], value)
+		}
+	}
+
+	var outputs = taskclient.Outputs{}
+	for name, values := range outputValues {
+		value, err := json.Marshal(values)
+		if err != nil {
+			return err
+		}
+		outputs[name] = apistructs.Output{
+			Name:  name,
+			Value: string(value),
+			Type:  apistructs.EnvType,
+		}
+	}
+	node.setTask(WithExtraOutputs(outputs))
+
+	if anyFailed {
+		return node.setDbTask(WithStatus(apistructs.FailedTaskStatus))
+	}
+	if !allSuccess {
+		return node.setDbTask(WithStatus(apistructs.CancelTaskStatus))
+	}
+	if allSkipped {
+		return node.setDbTask(WithStatus(apistructs.SkippedTaskStatus))
+	}
+	return node.setDbTask(WithStatus(apistructs.SuccessTaskStatus))
+}
+
+func (node *Node) buildMatrixInstances() ([]matrixInstance, error) {
+	matrix := node.taskDefinition.Matrix
+
+	var fromValue string
+	if matrix.From != "" {
+		replaceValue, err := node.buildReplaceValue()
+		if err != nil {
+			return nil, err
+		}
+		fromValue = placeholder.ReplacePlaceholder(matrix.From, replaceValue, false)
+	}
+
+	combinations, err := matrix.Combinations(fromValue)
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []matrixInstance
+	for index, values := range combinations {
+		definition, err := node.taskDefinition.MatrixInstance(index, values)
+		if err != nil {
+			return nil, err
+		}
+		instances = append(instances, matrixInstance{
+			definition: definition,
+			values:     values,
+		})
+	}
+	return instances, nil
+}
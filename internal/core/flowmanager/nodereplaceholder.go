@@ -16,7 +16,7 @@ package flowmanager
 import (
 	"eventops/apistructs"
 	"eventops/pkg/placeholder"
-	"eventops/pkg/schema/event"
+	"eventops/pkg/schema/pipeline"
 	"fmt"
 	"github.com/tidwall/gjson"
 	"gopkg.in/yaml.v3"
@@ -30,23 +30,13 @@ func (node *Node) getPlaceholderInputsValue() (apistructs.Inputs, error) {
 
 	var inputs = apistructs.Inputs{}
 	if parentNode == node.flow.rootNode {
-		extra := node.flow.getPipeExtra()
-		dbTriggerDefinition := extra.TriggerDefinitionContent
-		dbEvent := extra.EventContent
+		dbEvent := node.flow.getPipeExtra().EventContent
 
-		var triggerDefinition event.Trigger
-		err := yaml.Unmarshal([]byte(dbTriggerDefinition.Content), &triggerDefinition)
+		triggerDefinitionPipe, err := node.flow.getTriggerPipeline()
 		if err != nil {
 			return nil, fmt.Errorf("task alias: %v parent_task_id: %v yaml unmarshal triggerDefinition error: %v", node.getTask().Alias, node.parentTaskId, err.Error())
 		}
 
-		var triggerDefinitionPipe event.TriggerPipeline
-		for _, pipe := range triggerDefinition.Pipelines {
-			if pipe.Image == node.flow.rootNode.image {
-				triggerDefinitionPipe = pipe
-			}
-		}
-
 		var triggerDefinitionPipeInputMap = make(map[string]string, len(triggerDefinitionPipe.Inputs))
 		for _, input := range triggerDefinitionPipe.Inputs {
 			inputValue := gjson.Get(dbEvent.Content, input.Value).String()
@@ -102,25 +92,51 @@ func (node *Node) getPlaceholderContextValue() apistructs.Contexts {
 	return contexts
 }
 
-func (node *Node) getPlaceholderOutputValue() (apistructs.Outputs, error) {
-	if node == node.flow.rootNode {
-		return nil, nil
-	}
-
+// getPlaceholderMatchString 返回任务定义中可以使用 outputs 和 tasks 占位符的内容
+func (node *Node) getPlaceholderMatchString() (string, error) {
 	var matchString string
 	if node.taskDefinition.Type == apistructs.PipeType {
 		inputsYaml, err := yaml.Marshal(node.taskDefinition.Inputs)
 		if err != nil {
-			return nil, err
+			return "", err
 		}
 		matchString = string(inputsYaml)
 	} else {
 		commandsYaml, err := yaml.Marshal(node.taskDefinition.Commands)
 		if err != nil {
-			return nil, err
+			return "", err
 		}
 		matchString = string(commandsYaml)
 	}
+	matchString = matchString + node.taskDefinition.When
+
+	if node.taskDefinition.Approval != nil {
+		matchString = matchString + node.taskDefinition.Approval.Message
+	}
+
+	if node.taskDefinition.Matrix != nil {
+		matchString = matchString + node.taskDefinition.Matrix.From
+	}
+
+	if len(node.taskDefinition.Caches) > 0 {
+		cachesYaml, err := yaml.Marshal(node.taskDefinition.Caches)
+		if err != nil {
+			return "", err
+		}
+		matchString = matchString + string(cachesYaml)
+	}
+	return matchString, nil
+}
+
+func (node *Node) getPlaceholderOutputValue() (apistructs.Outputs, error) {
+	if node == node.flow.rootNode {
+		return nil, nil
+	}
+
+	matchString, err := node.getPlaceholderMatchString()
+	if err != nil {
+		return nil, err
+	}
 
 	var outputTaskNames []string
 	_ = placeholder.MatchHolderFromHandler(matchString, map[placeholder.Type]placeholder.Handler{
@@ -151,3 +167,27 @@ func (node *Node) getPlaceholderOutputValue() (apistructs.Outputs, error) {
 
 	return outputs, nil
 }
+
+func (node *Node) getPlaceholderTaskValue() (map[string]string, error) {
+	if node == node.flow.rootNode {
+		return nil, nil
+	}
+
+	matchString, err := node.getPlaceholderMatchString()
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks = map[string]string{}
+	_ = placeholder.MatchHolderFromHandler(matchString, map[placeholder.Type]placeholder.Handler{
+		placeholder.TaskType: func(holder string, values ...string) error {
+			task := node.flow.getTask(node.parentTaskId, values[1])
+			if task == nil {
+				return nil
+			}
+			tasks[placeholder.MakeOutputKey(values[1], pipeline.TaskStatusKey)] = string(task.Status)
+			return nil
+		},
+	})
+	return tasks, nil
+}
@@ -0,0 +1,199 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"eventops/apistructs"
+	"eventops/internal/core/client/pipelineclient"
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+// pause 暂停后运行中的任务继续执行到结束, 但是不再创建新的任务
+func (p *Flow) pause() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.stopStatus != "" || !p.dbPipe.Status.IsRunning() {
+		return fmt.Errorf("pipeline %v status %v can not pause", p.dbPipe.Id, p.dbPipe.Status)
+	}
+
+	now := time.Now()
+	p.dbPipe.Status = apistructs.PipelinePausedStatus
+	p.getExtraInfo().PausedAt = &now
+	if err := p.updatePipeAndExtra(); err != nil {
+		p.dbPipe.Status = apistructs.PipelineRunningStatus
+		p.getExtraInfo().PausedAt = nil
+		return err
+	}
+	p.pauseChan = make(chan struct{})
+	return nil
+}
+
+func (p *Flow) resume() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.stopStatus != "" || p.pauseChan == nil {
+		return fmt.Errorf("pipeline %v status %v can not resume", p.dbPipe.Id, p.dbPipe.Status)
+	}
+
+	extra := p.getExtraInfo()
+	pausedAt, pausedSec := extra.PausedAt, extra.PausedSec
+	if pausedAt != nil {
+		extra.PausedSec += uint64(time.Since(*pausedAt) / time.Second)
+		extra.PausedAt = nil
+	}
+	p.dbPipe.Status = apistructs.PipelineRunningStatus
+	if err := p.updatePipeAndExtra(); err != nil {
+		p.dbPipe.Status = apistructs.PipelinePausedStatus
+		extra.PausedAt, extra.PausedSec = pausedAt, pausedSec
+		return err
+	}
+	close(p.pauseChan)
+	p.pauseChan = nil
+	return nil
+}
+
+func (p *Flow) getExtraInfo() *pipelineclient.PipelineExtraInfo {
+	if p.dbPipeExtra.Extra == nil {
+		p.dbPipeExtra.Extra = &pipelineclient.PipelineExtraInfo{}
+	}
+	return p.dbPipeExtra.Extra
+}
+
+func (p *Flow) updatePipeAndExtra() error {
+	return p.flowManager.clientManager.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := p.flowManager.clientManager.pipelineClient.UpdatePipelineExtra(tx, p.dbPipeExtra); err != nil {
+			return err
+		}
+		_, err := p.flowManager.clientManager.pipelineClient.UpdatePipeline(tx, p.dbPipe)
+		return err
+	})
+}
+
+// runningDuration 流水线开始后除去暂停的运行时间, 暂停中返回 false
+func (p *Flow) runningDuration() (time.Duration, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.pauseChan != nil {
+		return 0, false
+	}
+	var pausedSec uint64
+	if p.dbPipeExtra.Extra != nil {
+		pausedSec = p.dbPipeExtra.Extra.PausedSec
+	}
+	return time.Since(*p.dbPipe.TimeBegin) - time.Duration(pausedSec)*time.Second, true
+}
+
+// waitResume 流水线暂停时等待恢复, 流水线结束时返回 false
+func (p *Flow) waitResume() bool {
+	p.lock.Lock()
+	pauseChan := p.pauseChan
+	p.lock.Unlock()
+
+	if pauseChan == nil {
+		return true
+	}
+
+	select {
+	case <-pauseChan:
+		return true
+	case <-p.getCtx().Done():
+		return false
+	}
+}
+
+// PauseFlow 暂停运行中的流水线
+func (m *FlowManager) PauseFlow(id uint64, user string) error {
+	_, find, err := m.clientManager.pipelineClient.GetPipeline(nil, id, user)
+	if err != nil {
+		return err
+	}
+	if !find {
+		return fmt.Errorf("not find this runtime: %v", id)
+	}
+
+	flow := m.GetFlow(id)
+	if flow == nil {
+		return fmt.Errorf("not find running pipeline %v", id)
+	}
+	return flow.pause()
+}
+
+// ResumeFlow 恢复暂停的流水线
+func (m *FlowManager) ResumeFlow(id uint64, user string) error {
+	dbPipeline, find, err := m.clientManager.pipelineClient.GetPipeline(nil, id, user)
+	if err != nil {
+		return err
+	}
+	if !find {
+		return fmt.Errorf("not find this runtime: %v", id)
+	}
+
+	flow, err := m.loadPausedFlow(dbPipeline)
+	if err != nil {
+		return err
+	}
+	return flow.resume()
+}
+
+// CancelPausedPipeline 取消服务重启后不在内存中的暂停的流水线, 重新加载后取消运行中的任务
+func (m *FlowManager) CancelPausedPipeline(id uint64, user string) error {
+	dbPipeline, find, err := m.clientManager.pipelineClient.GetPipeline(nil, id, user)
+	if err != nil {
+		return err
+	}
+	if !find {
+		return fmt.Errorf("not find this runtime: %v", id)
+	}
+
+	flow, err := m.loadPausedFlow(dbPipeline)
+	if err != nil {
+		return err
+	}
+	flow.lazyStopPipeline(apistructs.PipelineCancelStatus, fmt.Sprintf("user: %v stop", user))
+	return nil
+}
+
+// loadPausedFlow 服务启动时不会恢复暂停的流水线, 恢复或者取消的时候才重新加载, 加载后的流水线仍然是暂停状态
+func (m *FlowManager) loadPausedFlow(dbPipeline *pipelineclient.Pipeline) (*Flow, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if flow := m.flows[dbPipeline.Id]; flow != nil {
+		return flow, nil
+	}
+	if !dbPipeline.Status.IsPaused() {
+		return nil, fmt.Errorf("pipeline %v status %v not %v", dbPipeline.Id, dbPipeline.Status, apistructs.PipelinePausedStatus)
+	}
+
+	dbPipelineExtra, find, err := m.clientManager.pipelineClient.GetPipelineExtra(nil, dbPipeline.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !find {
+		return nil, fmt.Errorf("not find pipelineId: %v pipelineExtra", dbPipeline.Id)
+	}
+
+	flow, err := newFlow(m, dbPipeline, dbPipelineExtra)
+	if err != nil {
+		return nil, err
+	}
+	m.flows[dbPipeline.Id] = flow
+	go m.runFlow(flow)
+	return flow, nil
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"encoding/json"
+	"eventops/apistructs"
+	"eventops/conf"
+	"eventops/internal/core/client/eventclient"
+	"eventops/pkg/placeholder"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"strconv"
+	"time"
+)
+
+// 流水线结束后发布的事件, 触发器可以监听这个事件实现流水线的串联
+const (
+	PipelineFinishedEventName    = apistructs.ReservedEventNamePrefix + "pipeline.finished"
+	PipelineFinishedEventVersion = "1.0"
+)
+
+type EventHandler func(event eventclient.Event)
+
+// SetEventHandler 设置流水线发布事件后的处理函数, 一般是将事件交给 eventprocess 处理
+func (m *FlowManager) SetEventHandler(handler EventHandler) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.eventHandler = handler
+}
+
+func (m *FlowManager) getEventHandler() EventHandler {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	return m.eventHandler
+}
+
+type PipelineFinishedPayload struct {
+	Outputs map[string]string `json:"outputs"`
+}
+
+func (p *Flow) publishFinishedEvent() {
+	maxChainDepth := conf.GetEvent().MaxChainDepth
+	if maxChainDepth <= 0 {
+		return
+	}
+
+	dbPipe := p.getPipe()
+	chainDepth := p.getEventChainDepth() + 1
+	if chainDepth > maxChainDepth {
+		logrus.Warnf("pipeline %v chain depth %v greater than %v, not publish %v event", dbPipe.Id, chainDepth, maxChainDepth, PipelineFinishedEventName)
+		return
+	}
+
+	payload, err := json.Marshal(PipelineFinishedPayload{Outputs: p.buildPipelineOutputs()})
+	if err != nil {
+		logrus.Errorf("pipeline %v marshal %v event payload error: %v", dbPipe.Id, PipelineFinishedEventName, err)
+		return
+	}
+
+	var values = map[string]apistructs.Value{
+		"pipelineId":        apistructs.Value(strconv.FormatUint(dbPipe.Id, 10)),
+		"status":            apistructs.Value(dbPipe.Status),
+		"definitionName":    apistructs.Value(dbPipe.DefinitionName),
+		"definitionVersion": apistructs.Value(dbPipe.DefinitionVersion),
+		"definitionCreater": apistructs.Value(dbPipe.DefinitionCreater),
+	}
+	for key, value := range values {
+		if value == "" {
+			delete(values, key)
+		}
+	}
+
+	var eventInfo = apistructs.Event{
+		Name:         PipelineFinishedEventName,
+		Version:      PipelineFinishedEventVersion,
+		Values:       values,
+		Timestamp:    time.Now().Unix(),
+		SupportUsers: []string{dbPipe.Creater},
+		Payload:      payload,
+		ChainDepth:   chainDepth,
+		// 同一个流水线只发布一次
+		IdempotencyKey: fmt.Sprintf("pipeline-%v", dbPipe.Id),
+	}
+	content, err := json.Marshal(eventInfo)
+	if err != nil {
+		logrus.Errorf("pipeline %v marshal %v event error: %v", dbPipe.Id, PipelineFinishedEventName, err)
+		return
+	}
+	contentHash, err := eventInfo.ContentHash()
+	if err != nil {
+		logrus.Errorf("pipeline %v hash %v event error: %v", dbPipe.Id, PipelineFinishedEventName, err)
+		return
+	}
+
+	createEvent := eventclient.Event{
+		Name:           eventInfo.Name,
+		Version:        eventInfo.Version,
+		Content:        string(content),
+		Creater:        dbPipe.Creater,
+		Status:         apistructs.EventCreatedStatus,
+		IdempotencyKey: eventInfo.IdempotencyKey,
+		ContentHash:    contentHash,
+	}
+	if _, err := p.flowManager.clientManager.eventClient.CreateEvent(nil, &createEvent); err != nil {
+		logrus.Errorf("pipeline %v create %v event error: %v", dbPipe.Id, PipelineFinishedEventName, err)
+		return
+	}
+
+	if handler := p.flowManager.getEventHandler(); handler != nil {
+		handler(createEvent)
+	}
+}
+
+// getEventChainDepth 触发当前流水线的事件的串联深度
+func (p *Flow) getEventChainDepth() int {
+	dbPipeExtra := p.getPipeExtra()
+	if dbPipeExtra == nil || dbPipeExtra.EventContent == nil {
+		return 0
+	}
+
+	var eventInfo apistructs.Event
+	if err := json.Unmarshal([]byte(dbPipeExtra.EventContent.Content), &eventInfo); err != nil {
+		return 0
+	}
+	return eventInfo.ChainDepth
+}
+
+// buildPipelineOutputs 按照流水线定义中的 outputs 从根流水线的任务中取值
+func (p *Flow) buildPipelineOutputs() map[string]string {
+	var outputs = map[string]string{}
+
+	definition, err := p.getAndSetPipelineVersionDefinition(p.rootNode.taskDefinition.Image)
+	if err != nil {
+		return outputs
+	}
+
+	for _, definitionOutput := range definition.Outputs {
+		_ = placeholder.MatchHolderFromHandler(definitionOutput.Value, map[placeholder.Type]placeholder.Handler{
+			placeholder.OutputType: func(placeholder string, values ...string) error {
+				outputTask := p.getTask(p.rootNode.getTask().Id, values[1])
+				if outputTask == nil || outputTask.Outputs == nil {
+					return nil
+				}
+				for _, taskOutput := range *outputTask.Outputs {
+					if taskOutput.Name == values[2] {
+						outputs[definitionOutput.Name] = taskOutput.Value
+					}
+				}
+				return nil
+			},
+		})
+	}
+	return outputs
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"eventops/apistructs"
+	"eventops/internal/core/client/pipelineclient"
+	"eventops/internal/core/client/taskclient"
+	"eventops/pkg/schema/pipeline"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"sort"
+)
+
+// RerunPipeline 使用已经结束的流水线保存的定义、事件和触发器重新创建一条流水线
+// fromFailed 为 true 时复制执行成功的任务, 新的流水线只会重新执行失败的任务和它的下游任务
+func (m *FlowManager) RerunPipeline(dbPipeline *pipelineclient.Pipeline, fromFailed bool) (*pipelineclient.Pipeline, error) {
+	if !dbPipeline.Status.IsEnd() {
+		return nil, fmt.Errorf("pipeline %v status %v not end", dbPipeline.Id, dbPipeline.Status)
+	}
+
+	if m.GetFlow(dbPipeline.Id) != nil {
+		return nil, fmt.Errorf("pipeline %v is stopping", dbPipeline.Id)
+	}
+
+	dbPipelineExtra, find, err := m.clientManager.pipelineClient.GetPipelineExtra(nil, dbPipeline.Id)
+	if err != nil {
+		return nil, err
+	}
+	if !find {
+		return nil, fmt.Errorf("not find pipelineId: %v pipelineExtra", dbPipeline.Id)
+	}
+
+	var dbTasks []*taskclient.Task
+	var finallyAlias = map[string]bool{}
+	if fromFailed {
+		var definition pipeline.Pipeline
+		if err := yaml.Unmarshal([]byte(dbPipelineExtra.DefinitionContent.Content), &definition); err != nil {
+			return nil, err
+		}
+		// finally 任务每次都需要重新执行
+		for _, task := range definition.Finally {
+			finallyAlias[task.Alias] = true
+		}
+
+		dbTasks, err = m.clientManager.taskClient.ListTasks(nil, dbPipeline.Id, dbPipeline.Creater)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 不复制 EventTriggerId, 一个事件触发只对应原来的流水线, 重新执行的流水线只通过 rerunFrom 关联
+	newPipeline := &pipelineclient.Pipeline{
+		EventId:             dbPipeline.EventId,
+		TriggerDefinitionId: dbPipeline.TriggerDefinitionId,
+		DefinitionName:      dbPipeline.DefinitionName,
+		DefinitionCreater:   dbPipeline.DefinitionCreater,
+		DefinitionVersion:   dbPipeline.DefinitionVersion,
+		Creater:             dbPipeline.Creater,
+		Status:              apistructs.PipelineRunningStatus,
+		CostTimeSec:         0,
+		ConcurrencyGroup:    dbPipeline.ConcurrencyGroup,
+		Priority:            dbPipeline.Priority,
+	}
+
+	var contexts = pipelineclient.PipelineExtraContents{}
+	if fromFailed && dbPipelineExtra.Contexts != nil {
+		for key, value := range *dbPipelineExtra.Contexts {
+			contexts[key] = value
+		}
+	}
+	newPipelineExtra := &pipelineclient.PipelineExtra{
+		DefinitionContent:        dbPipelineExtra.DefinitionContent,
+		EventContent:             dbPipelineExtra.EventContent,
+		EventTriggerContent:      dbPipelineExtra.EventTriggerContent,
+		TriggerDefinitionContent: dbPipelineExtra.TriggerDefinitionContent,
+		Extra:                    &pipelineclient.PipelineExtraInfo{RerunFrom: dbPipeline.Id},
+		Contexts:                 &contexts,
+	}
+
+	// 重新执行的流水线在并发组中排队
+	m.concurrencyLock.Lock()
+	defer m.concurrencyLock.Unlock()
+	if err := m.admitPipeline(newPipeline, newPipelineExtra, pipeline.QueueConcurrencyPolicy); err != nil {
+		return nil, err
+	}
+
+	var flow *Flow
+	err = m.clientManager.db.Transaction(func(tx *gorm.DB) error {
+		_, err := m.clientManager.pipelineClient.CreatePipeline(tx, newPipeline)
+		if err != nil {
+			return err
+		}
+
+		newPipelineExtra.PipelineId = newPipeline.Id
+		_, err = m.clientManager.pipelineClient.CreatePipelineExtra(tx, newPipelineExtra)
+		if err != nil {
+			return err
+		}
+
+		if err := m.copySuccessTasks(tx, newPipeline.Id, dbTasks, finallyAlias); err != nil {
+			return err
+		}
+
+		if !newPipeline.Status.IsRunning() {
+			return nil
+		}
+
+		flow, err = newFlow(m, newPipeline, newPipelineExtra)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if flow != nil {
+		go m.runFlow(flow)
+	}
+	return newPipeline, nil
+}
+
+func (m *FlowManager) copySuccessTasks(tx *gorm.DB, pipelineId uint64, dbTasks []*taskclient.Task, finallyAlias map[string]bool) error {
+	return copyTasks(pipelineId, dbTasks, finallyAlias, func(task *taskclient.Task) error {
+		_, err := m.clientManager.taskClient.CreateTask(tx, task)
+		return err
+	})
+}
+
+// copyTasks 成功和跳过的任务原样复制, pipeline 类型的任务重置为初始化状态, 让它下面成功的任务可以挂到新的任务 id 上
+// createTask 需要设置新任务的 id
+func copyTasks(pipelineId uint64, dbTasks []*taskclient.Task, finallyAlias map[string]bool, createTask func(task *taskclient.Task) error) error {
+	// 父任务总是比子任务先创建
+	sort.Slice(dbTasks, func(i, j int) bool {
+		return dbTasks[i].Id < dbTasks[j].Id
+	})
+
+	var taskIdMap = map[uint64]uint64{0: 0}
+	for _, dbTask := range dbTasks {
+		parentTaskId, ok := taskIdMap[dbTask.ParentTaskId]
+		if !ok {
+			continue
+		}
+		if dbTask.ParentTaskId == 0 && finallyAlias[dbTask.Alias] {
+			continue
+		}
+
+		done := dbTask.Status == apistructs.SuccessTaskStatus || dbTask.Status == apistructs.SkippedTaskStatus
+		if !done && dbTask.Type != apistructs.PipeType {
+			continue
+		}
+
+		var extra = taskclient.TaskExtra{}
+		if dbTask.Extra != nil {
+			extra = *dbTask.Extra
+		}
+		newTask := &taskclient.Task{
+			PipelineId:   pipelineId,
+			ParentTaskId: parentTaskId,
+			Alias:        dbTask.Alias,
+			Type:         dbTask.Type,
+			Status:       dbTask.Status,
+			Extra:        &extra,
+			Outputs:      dbTask.Outputs,
+			CostTimeSec:  dbTask.CostTimeSec,
+			TimeBegin:    dbTask.TimeBegin,
+			TimeEnd:      dbTask.TimeEnd,
+			Creater:      dbTask.Creater,
+			// 复制的任务没有在执行器上创建资源
+			Cleaned: true,
+		}
+		if !done {
+			newTask.Status = apistructs.InitTaskStatus
+			newTask.Extra.Error = ""
+			newTask.Outputs = &taskclient.Outputs{}
+			newTask.CostTimeSec = 0
+			newTask.TimeBegin = nil
+			newTask.TimeEnd = nil
+			newTask.Cleaned = false
+		}
+		if newTask.Outputs == nil {
+			newTask.Outputs = &taskclient.Outputs{}
+		}
+
+		if err := createTask(newTask); err != nil {
+			return err
+		}
+		taskIdMap[dbTask.Id] = newTask.Id
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"eventops/apistructs"
+	"eventops/internal/core/client/taskclient"
+	"testing"
+	"time"
+)
+
+func TestCopyTasks(t *testing.T) {
+	now := time.Now()
+	outputs := taskclient.Outputs{"version": {Name: "version", Value: "1.0", Type: apistructs.EnvType}}
+	newTask := func(id, parentTaskId uint64, alias string, taskType apistructs.TaskType, status apistructs.TaskStatus) *taskclient.Task {
+		return &taskclient.Task{
+			Id:           id,
+			PipelineId:   1,
+			ParentTaskId: parentTaskId,
+			Alias:        alias,
+			Type:         taskType,
+			Status:       status,
+			Outputs:      &outputs,
+			Extra:        &taskclient.TaskExtra{Error: "error"},
+			TimeBegin:    &now,
+			TimeEnd:      &now,
+			CostTimeSec:  10,
+		}
+	}
+
+	// 乱序传入, 父任务总是比子任务先创建
+	var source = newTask(2, 0, "sub", apistructs.PipeType, apistructs.FailedTaskStatus)
+	var dbTasks = []*taskclient.Task{
+		newTask(3, 2, "child-success", apistructs.DockerType, apistructs.SuccessTaskStatus),
+		newTask(1, 0, "build", apistructs.DockerType, apistructs.SuccessTaskStatus),
+		source,
+		newTask(4, 2, "child-failed", apistructs.DockerType, apistructs.FailedTaskStatus),
+		newTask(5, 0, "test", apistructs.DockerType, apistructs.FailedTaskStatus),
+		newTask(6, 0, "matrix", apistructs.DockerType, apistructs.FailedTaskStatus),
+		newTask(7, 0, "matrix-0", apistructs.DockerType, apistructs.SuccessTaskStatus),
+		newTask(8, 0, "matrix-1", apistructs.DockerType, apistructs.FailedTaskStatus),
+		newTask(9, 0, "notify", apistructs.DockerType, apistructs.SuccessTaskStatus),
+		newTask(10, 5, "orphan", apistructs.DockerType, apistructs.SuccessTaskStatus),
+		newTask(11, 0, "lint", apistructs.DockerType, apistructs.SkippedTaskStatus),
+	}
+
+	var created = map[string]*taskclient.Task{}
+	var nextId uint64 = 100
+	err := copyTasks(2, dbTasks, map[string]bool{"notify": true}, func(task *taskclient.Task) error {
+		task.Id = nextId
+		nextId++
+		created[task.Alias] = task
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("copy tasks error: %v", err)
+	}
+
+	if len(created) != 5 {
+		t.Fatalf("created tasks %v, want build sub child-success matrix-0 lint", created)
+	}
+	for _, alias := range []string{"child-failed", "test", "matrix", "matrix-1", "notify", "orphan"} {
+		if _, ok := created[alias]; ok {
+			t.Fatalf("task %v should not copy", alias)
+		}
+	}
+
+	for _, alias := range []string{"build", "child-success", "matrix-0", "lint"} {
+		task := created[alias]
+		if task == nil || task.PipelineId != 2 || !task.Cleaned || task.TimeBegin == nil || task.CostTimeSec != 10 || (*task.Outputs)["version"].Value != "1.0" {
+			t.Fatalf("task %v copy %v not match", alias, task)
+		}
+	}
+	if created["lint"].Status != apistructs.SkippedTaskStatus {
+		t.Fatalf("skipped task status %v should keep", created["lint"].Status)
+	}
+
+	// pipeline 类型的任务重新执行, 它下面成功的任务挂到新的任务 id 上
+	sub := created["sub"]
+	if sub.Status != apistructs.InitTaskStatus || sub.Cleaned || sub.TimeBegin != nil || sub.TimeEnd != nil ||
+		sub.CostTimeSec != 0 || sub.Extra.Error != "" || len(*sub.Outputs) != 0 {
+		t.Fatalf("pipeline task %v should reset", sub)
+	}
+	if created["child-success"].ParentTaskId != sub.Id || created["build"].ParentTaskId != 0 {
+		t.Fatalf("parent task id not remap")
+	}
+
+	// 复制的任务不能修改原来的任务
+	if source.Extra.Error != "error" || source.Status != apistructs.FailedTaskStatus {
+		t.Fatalf("source task %v changed", source)
+	}
+}
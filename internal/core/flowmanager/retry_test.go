@@ -0,0 +1,108 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"eventops/apistructs"
+	"eventops/internal/core/client/taskclient"
+	"eventops/pkg/schema/pipeline"
+	"testing"
+	"time"
+)
+
+func newTestNode(task *taskclient.Task, definition *pipeline.Task) *Node {
+	flow := &Flow{dbTasks: map[string]*taskclient.Task{}}
+	flow.addTask(task)
+	return &Node{flow: flow, parentTaskId: task.ParentTaskId, taskDefinition: definition}
+}
+
+func TestCanRetry(t *testing.T) {
+	var retry = &pipeline.Retry{MaxAttempts: 3}
+	var testData = []struct {
+		name     string
+		retry    *pipeline.Retry
+		attempts int
+		status   apistructs.TaskStatus
+		want     bool
+	}{
+		{"no retry", nil, 0, apistructs.FailedTaskStatus, false},
+		{"failed", retry, 0, apistructs.FailedTaskStatus, true},
+		{"last attempt", retry, 1, apistructs.FailedTaskStatus, true},
+		{"max attempts", retry, 2, apistructs.FailedTaskStatus, false},
+		{"success", retry, 0, apistructs.SuccessTaskStatus, false},
+		{"cancel", retry, 0, apistructs.CancelTaskStatus, false},
+		{"timeout not in default on", retry, 0, apistructs.TimeoutTaskStatus, false},
+		{"timeout in on", &pipeline.Retry{MaxAttempts: 3, On: []apistructs.TaskStatus{apistructs.TimeoutTaskStatus}}, 0, apistructs.TimeoutTaskStatus, true},
+	}
+	for _, data := range testData {
+		task := &taskclient.Task{Alias: "a", Extra: &taskclient.TaskExtra{}}
+		for i := 0; i < data.attempts; i++ {
+			task.Extra.Attempts = append(task.Extra.Attempts, apistructs.TaskAttempt{Attempt: i + 1})
+		}
+		node := newTestNode(task, &pipeline.Task{Alias: "a", Retry: data.retry})
+		if got := node.canRetry(data.status); got != data.want {
+			t.Fatalf("%v: canRetry %v, want %v", data.name, got, data.want)
+		}
+	}
+}
+
+func TestWithAttempt(t *testing.T) {
+	begin := time.Now().Add(-time.Minute)
+	end := time.Now()
+	task := &taskclient.Task{
+		Alias:   "a",
+		Status:  apistructs.FailedTaskStatus,
+		JobSign: "job-1",
+		TimeEnd: &end,
+		Extra: &taskclient.TaskExtra{
+			Error:            "Exited (1)",
+			AttemptTimeBegin: &begin,
+		},
+	}
+
+	WithAttempt(apistructs.TaskAttempt{Attempt: 1, Status: apistructs.FailedTaskStatus, JobSign: "job-1"})(task)
+	if len(task.Extra.Attempts) != 1 || task.Extra.Attempts[0].JobSign != "job-1" {
+		t.Fatalf("attempts %v error", task.Extra.Attempts)
+	}
+	if task.Status != apistructs.InitTaskStatus || task.JobSign != "" || task.TimeEnd != nil || task.Extra.Error != "" {
+		t.Fatalf("task %v not reset after attempt", task)
+	}
+	if task.Extra.AttemptTimeBegin != nil {
+		t.Fatalf("attempt time begin should reset")
+	}
+}
+
+func TestAttemptTimeBegin(t *testing.T) {
+	taskBegin := time.Now().Add(-time.Hour)
+	attemptEnd := time.Now().Add(-time.Minute)
+	attemptBegin := time.Now()
+
+	task := &taskclient.Task{Alias: "a", TimeBegin: &taskBegin, Extra: &taskclient.TaskExtra{}}
+	node := newTestNode(task, &pipeline.Task{Alias: "a"})
+	if !node.attemptTimeBegin().Equal(taskBegin) {
+		t.Fatalf("first attempt should begin at task time begin")
+	}
+
+	// 重试等待期间 job 还没有创建, 使用上一次执行的结束时间
+	WithAttempt(apistructs.TaskAttempt{Attempt: 1, TimeEnd: &attemptEnd})(task)
+	if !node.attemptTimeBegin().Equal(attemptEnd) {
+		t.Fatalf("attempt without job should begin at last attempt time end")
+	}
+
+	// job 创建后超时时间从创建时间开始计算, 不包含重试等待的时间
+	WithAttemptTimeBegin(attemptBegin)(task)
+	if !node.attemptTimeBegin().Equal(attemptBegin) {
+		t.Fatalf("attempt should begin at job created time")
+	}
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"eventops/apistructs"
+	"eventops/internal/core/client/pipelineclient"
+	"eventops/pkg/schema/event"
+	"github.com/tidwall/gjson"
+	"sort"
+	"strconv"
+)
+
+// resolvePriority 事件中的 priority 标签优先于触发器中声明的优先级
+func resolvePriority(data *AssociatedData, triggerPipe event.TriggerPipeline) int {
+	label := gjson.Get(data.event.Content, "labels."+apistructs.EventPriorityLabel)
+	if label.Exists() {
+		if priority, err := strconv.Atoi(label.String()); err == nil {
+			return priority
+		}
+	}
+	return triggerPipe.Priority
+}
+
+// sortQueuedPipelines 优先级高的先执行, 优先级相同时运行中的流水线数量和权重的比值小的用户先执行, 最后按照创建顺序
+func (u *runningUsage) sortQueuedPipelines(dbPipelines []*pipelineclient.Pipeline) {
+	sort.SliceStable(dbPipelines, func(i, j int) bool {
+		a, b := dbPipelines[i], dbPipelines[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+
+		aShare := u.users[a.Creater] * u.quotas[b.Creater].weight
+		bShare := u.users[b.Creater] * u.quotas[a.Creater].weight
+		if aShare != bShare {
+			return aShare < bShare
+		}
+		return a.Id < b.Id
+	})
+}
+
+// plan 返回现在可以执行的流水线, 以及继续排队的流水线的调度信息
+func (u *runningUsage) plan(queuedPipelines []pipelineclient.Pipeline) ([]*pipelineclient.Pipeline, map[uint64]apistructs.PipelineSchedule, error) {
+	var waitPipelines []*pipelineclient.Pipeline
+	for index := range queuedPipelines {
+		if _, err := u.getUserQuota(queuedPipelines[index].Creater); err != nil {
+			return nil, nil, err
+		}
+		waitPipelines = append(waitPipelines, &queuedPipelines[index])
+	}
+
+	// 每执行一条流水线后用户的份额都会变化, 需要重新排序
+	var runPipelines []*pipelineclient.Pipeline
+	for {
+		u.sortQueuedPipelines(waitPipelines)
+
+		var runIndex = -1
+		for index, dbPipeline := range waitPipelines {
+			reason, err := u.waitReason(dbPipeline)
+			if err != nil {
+				return nil, nil, err
+			}
+			if reason == "" {
+				runIndex = index
+				break
+			}
+		}
+		if runIndex < 0 {
+			break
+		}
+
+		runPipelines = append(runPipelines, waitPipelines[runIndex])
+		u.add(waitPipelines[runIndex])
+		waitPipelines = append(waitPipelines[:runIndex], waitPipelines[runIndex+1:]...)
+	}
+
+	var schedules = map[uint64]apistructs.PipelineSchedule{}
+	for index, dbPipeline := range runPipelines {
+		schedules[dbPipeline.Id] = apistructs.PipelineSchedule{
+			Priority: dbPipeline.Priority,
+			Position: index + 1,
+			Reason:   "waiting for scheduler",
+		}
+	}
+	for index, dbPipeline := range waitPipelines {
+		reason, err := u.waitReason(dbPipeline)
+		if err != nil {
+			return nil, nil, err
+		}
+		schedules[dbPipeline.Id] = apistructs.PipelineSchedule{
+			Priority: dbPipeline.Priority,
+			Position: len(runPipelines) + index + 1,
+			Reason:   reason,
+		}
+	}
+	return runPipelines, schedules, nil
+}
+
+// GetPipelineSchedule 按照当前运行中的流水线计算排队的流水线的调度信息, 流水线没有排队时返回 nil
+func (m *FlowManager) GetPipelineSchedule(id uint64) (*apistructs.PipelineSchedule, error) {
+	queuedPipelines, err := m.clientManager.pipelineClient.ListPipeline(nil, pipelineclient.ListPipelineQuery{
+		Statuses:     []apistructs.PipelineStatus{apistructs.PipelineQueuedStatus},
+		OrderByIdAsc: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := m.getRunningUsage()
+	if err != nil {
+		return nil, err
+	}
+
+	_, schedules, err := usage.plan(queuedPipelines)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, ok := schedules[id]
+	if !ok {
+		return nil, nil
+	}
+	return &schedule, nil
+}
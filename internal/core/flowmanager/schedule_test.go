@@ -0,0 +1,172 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package flowmanager
+
+import (
+	"eventops/internal/core/client/pipelineclient"
+	"reflect"
+	"testing"
+)
+
+func newTestUsage(maxRunningPipelines int, quotas map[string]userQuota, runningPipelines ...pipelineclient.Pipeline) *runningUsage {
+	usage := &runningUsage{
+		users:               map[string]int{},
+		groups:              map[string]bool{},
+		quotas:              quotas,
+		maxRunningPipelines: maxRunningPipelines,
+	}
+	for index := range runningPipelines {
+		usage.add(&runningPipelines[index])
+	}
+	return usage
+}
+
+func pipelineIds(dbPipelines []*pipelineclient.Pipeline) []uint64 {
+	var ids []uint64
+	for _, dbPipeline := range dbPipelines {
+		ids = append(ids, dbPipeline.Id)
+	}
+	return ids
+}
+
+func TestSortQueuedPipelines(t *testing.T) {
+	var testData = []struct {
+		name    string
+		quotas  map[string]userQuota
+		running []pipelineclient.Pipeline
+		queued  []pipelineclient.Pipeline
+		want    []uint64
+	}{
+		{
+			name:   "priority first",
+			quotas: map[string]userQuota{"a": {weight: 1}, "b": {weight: 1}},
+			queued: []pipelineclient.Pipeline{{Id: 1, Creater: "a"}, {Id: 2, Creater: "b", Priority: 5}, {Id: 3, Creater: "a", Priority: -1}},
+			want:   []uint64{2, 1, 3},
+		},
+		{
+			name:    "less running first",
+			quotas:  map[string]userQuota{"a": {weight: 1}, "b": {weight: 1}},
+			running: []pipelineclient.Pipeline{{Creater: "a"}},
+			queued:  []pipelineclient.Pipeline{{Id: 1, Creater: "a"}, {Id: 2, Creater: "b"}},
+			want:    []uint64{2, 1},
+		},
+		{
+			// a 的份额 3/2 大于 b 的份额 1/1
+			name:    "running divide weight",
+			quotas:  map[string]userQuota{"a": {weight: 2}, "b": {weight: 1}},
+			running: []pipelineclient.Pipeline{{Creater: "a"}, {Creater: "a"}, {Creater: "a"}, {Creater: "b"}},
+			queued:  []pipelineclient.Pipeline{{Id: 1, Creater: "a"}, {Id: 2, Creater: "b"}},
+			want:    []uint64{2, 1},
+		},
+		{
+			// a 的份额 1/4 小于 b 的份额 1/1
+			name:    "higher weight first",
+			quotas:  map[string]userQuota{"a": {weight: 4}, "b": {weight: 1}},
+			running: []pipelineclient.Pipeline{{Creater: "a"}, {Creater: "b"}},
+			queued:  []pipelineclient.Pipeline{{Id: 1, Creater: "b"}, {Id: 2, Creater: "a"}},
+			want:    []uint64{2, 1},
+		},
+		{
+			// 份额相同时按照创建顺序
+			name:    "same share",
+			quotas:  map[string]userQuota{"a": {weight: 2}, "b": {weight: 1}},
+			running: []pipelineclient.Pipeline{{Creater: "a"}, {Creater: "a"}, {Creater: "b"}},
+			queued:  []pipelineclient.Pipeline{{Id: 2, Creater: "a"}, {Id: 1, Creater: "b"}},
+			want:    []uint64{1, 2},
+		},
+	}
+	for _, data := range testData {
+		usage := newTestUsage(0, data.quotas, data.running...)
+		var queued []*pipelineclient.Pipeline
+		for index := range data.queued {
+			queued = append(queued, &data.queued[index])
+		}
+		usage.sortQueuedPipelines(queued)
+		if got := pipelineIds(queued); !reflect.DeepEqual(got, data.want) {
+			t.Fatalf("%v: sort %v, want %v", data.name, got, data.want)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	var testData = []struct {
+		name                string
+		maxRunningPipelines int
+		quotas              map[string]userQuota
+		running             []pipelineclient.Pipeline
+		queued              []pipelineclient.Pipeline
+		wantRun             []uint64
+		wantReasons         map[uint64]string
+	}{
+		{
+			// 每执行一条流水线后重新排序, a 的权重是 b 的两倍
+			name:    "weight share",
+			quotas:  map[string]userQuota{"a": {weight: 2}, "b": {weight: 1}},
+			queued:  []pipelineclient.Pipeline{{Id: 1, Creater: "a"}, {Id: 2, Creater: "a"}, {Id: 3, Creater: "a"}, {Id: 4, Creater: "b"}, {Id: 5, Creater: "b"}},
+			wantRun: []uint64{1, 4, 2, 3, 5},
+		},
+		{
+			name:                "global max running",
+			maxRunningPipelines: 2,
+			quotas:              map[string]userQuota{"a": {weight: 1}, "b": {weight: 1}},
+			running:             []pipelineclient.Pipeline{{Creater: "a"}},
+			queued:              []pipelineclient.Pipeline{{Id: 1, Creater: "a"}, {Id: 2, Creater: "b"}, {Id: 3, Creater: "b"}},
+			wantRun:             []uint64{2},
+			wantReasons:         map[uint64]string{1: "max running pipelines 2 reached", 3: "max running pipelines 2 reached"},
+		},
+		{
+			name:        "user max running",
+			quotas:      map[string]userQuota{"a": {weight: 1, maxRunningPipelines: 1}, "b": {weight: 1}},
+			queued:      []pipelineclient.Pipeline{{Id: 1, Creater: "a"}, {Id: 2, Creater: "a"}, {Id: 3, Creater: "b"}},
+			wantRun:     []uint64{1, 3},
+			wantReasons: map[uint64]string{2: "user a max running pipelines 1 reached"},
+		},
+		{
+			name:    "one running pipeline per group",
+			quotas:  map[string]userQuota{"a": {weight: 1}},
+			running: []pipelineclient.Pipeline{{Creater: "a", ConcurrencyGroup: "y"}},
+			queued: []pipelineclient.Pipeline{
+				{Id: 1, Creater: "a", ConcurrencyGroup: "x"},
+				{Id: 2, Creater: "a", ConcurrencyGroup: "x"},
+				{Id: 3, Creater: "a"},
+				{Id: 4, Creater: "a", ConcurrencyGroup: "y"},
+			},
+			wantRun:     []uint64{1, 3},
+			wantReasons: map[uint64]string{2: "concurrency group x has running pipeline", 4: "concurrency group y has running pipeline"},
+		},
+	}
+	for _, data := range testData {
+		usage := newTestUsage(data.maxRunningPipelines, data.quotas, data.running...)
+		runPipelines, schedules, err := usage.plan(data.queued)
+		if err != nil {
+			t.Fatalf("%v: plan error: %v", data.name, err)
+		}
+		if got := pipelineIds(runPipelines); !reflect.DeepEqual(got, data.wantRun) {
+			t.Fatalf("%v: run %v, want %v", data.name, got, data.wantRun)
+		}
+		if len(schedules) != len(data.queued) {
+			t.Fatalf("%v: schedules %v should have all queued pipelines", data.name, schedules)
+		}
+		for index, id := range data.wantRun {
+			if schedules[id].Position != index+1 {
+				t.Fatalf("%v: pipeline %v position %v, want %v", data.name, id, schedules[id].Position, index+1)
+			}
+		}
+		for id, reason := range data.wantReasons {
+			if schedules[id].Reason != reason || schedules[id].Position <= len(data.wantRun) {
+				t.Fatalf("%v: pipeline %v schedule %v, want reason %v", data.name, id, schedules[id], reason)
+			}
+		}
+	}
+}
@@ -34,6 +34,11 @@ func isWriteUrlList(url string) bool {
 			return true
 		}
 	}
+	for _, writeUrlPrefix := range conf.GetUc().Auth.WhiteUrlPrefixList {
+		if strings.HasPrefix(url, writeUrlPrefix) {
+			return true
+		}
+	}
 	return false
 }
 
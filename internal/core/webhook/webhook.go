@@ -0,0 +1,223 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"eventops/apistructs"
+	"eventops/pkg/schema/event"
+	"fmt"
+	"github.com/tidwall/gjson"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	GithubEventHeader     = "X-GitHub-Event"
+	GithubSignatureHeader = "X-Hub-Signature-256"
+	GithubDeliveryHeader  = "X-GitHub-Delivery"
+	GitlabEventHeader     = "X-Gitlab-Event"
+	GitlabTokenHeader     = "X-Gitlab-Token"
+	GitlabDeliveryHeader  = "X-Gitlab-Event-UUID"
+	GiteaEventHeader      = "X-Gitea-Event"
+	GiteaSignatureHeader  = "X-Gitea-Signature"
+	GiteaDeliveryHeader   = "X-Gitea-Delivery"
+)
+
+const (
+	branchRefPrefix = "refs/heads/"
+	tagRefPrefix    = "refs/tags/"
+)
+
+// Adapter 校验不同来源的 webhook 请求, 并从请求中解析出事件类型和 values
+type Adapter interface {
+	Verify(header http.Header, body []byte, secret string) error
+	EventType(header http.Header, payload []byte) (string, error)
+	// DeliveryId 每次投递的 id, 重新投递的时候不变
+	DeliveryId(header http.Header) string
+	// values 的名称和取值表达式, 按顺序取第一个有值的
+	ValuePaths() map[string][]string
+}
+
+var adapters = map[event.WebhookProvider]Adapter{
+	event.GithubWebhookProvider: githubAdapter{},
+	event.GitlabWebhookProvider: gitlabAdapter{},
+	event.GiteaWebhookProvider:  giteaAdapter{},
+}
+
+func GetAdapter(provider event.WebhookProvider) (Adapter, bool) {
+	adapter, ok := adapters[provider]
+	return adapter, ok
+}
+
+// GetPayload github 和 gitea 支持 application/x-www-form-urlencoded 格式, 这个时候 json 在 payload 字段中
+func GetPayload(header http.Header, body []byte) ([]byte, error) {
+	payload := body
+	if strings.HasPrefix(header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("parse form body error: %v", err)
+		}
+		payload = []byte(values.Get("payload"))
+	}
+
+	if !gjson.ValidBytes(payload) {
+		return nil, fmt.Errorf("payload is not json")
+	}
+	return payload, nil
+}
+
+// BuildValues 按照 adapter 的取值表达式从 payload 中取值, ref 会额外解析出 branch 或者 tag
+func BuildValues(adapter Adapter, payload []byte) map[string]apistructs.Value {
+	var values = map[string]apistructs.Value{}
+	for name, paths := range adapter.ValuePaths() {
+		for _, path := range paths {
+			value := gjson.GetBytes(payload, path).String()
+			if value != "" {
+				values[name] = apistructs.Value(value)
+				break
+			}
+		}
+	}
+
+	ref := string(values["ref"])
+	if strings.HasPrefix(ref, branchRefPrefix) {
+		values["branch"] = apistructs.Value(strings.TrimPrefix(ref, branchRefPrefix))
+	}
+	if strings.HasPrefix(ref, tagRefPrefix) {
+		values["tag"] = apistructs.Value(strings.TrimPrefix(ref, tagRefPrefix))
+	}
+	return values
+}
+
+func verifyHmacSha256(signature string, body []byte, secret string) error {
+	if signature == "" {
+		return fmt.Errorf("signature can not empty")
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("signature format error: %v", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return fmt.Errorf("signature not match")
+	}
+	return nil
+}
+
+var githubValuePaths = map[string][]string{
+	"ref":          {"ref"},
+	"commit":       {"after", "pull_request.head.sha"},
+	"repository":   {"repository.full_name"},
+	"sender":       {"sender.login"},
+	"action":       {"action"},
+	"number":       {"pull_request.number", "issue.number"},
+	"sourceBranch": {"pull_request.head.ref"},
+	"targetBranch": {"pull_request.base.ref"},
+}
+
+type githubAdapter struct{}
+
+func (githubAdapter) Verify(header http.Header, body []byte, secret string) error {
+	signature := header.Get(GithubSignatureHeader)
+	if !strings.HasPrefix(signature, "sha256=") {
+		return fmt.Errorf("header %v format error", GithubSignatureHeader)
+	}
+	return verifyHmacSha256(strings.TrimPrefix(signature, "sha256="), body, secret)
+}
+
+func (githubAdapter) EventType(header http.Header, payload []byte) (string, error) {
+	eventType := header.Get(GithubEventHeader)
+	if eventType == "" {
+		return "", fmt.Errorf("header %v can not empty", GithubEventHeader)
+	}
+	return eventType, nil
+}
+
+func (githubAdapter) DeliveryId(header http.Header) string {
+	return header.Get(GithubDeliveryHeader)
+}
+
+func (githubAdapter) ValuePaths() map[string][]string {
+	return githubValuePaths
+}
+
+// gitea 的 payload 和 github 基本兼容
+type giteaAdapter struct{}
+
+func (giteaAdapter) Verify(header http.Header, body []byte, secret string) error {
+	return verifyHmacSha256(header.Get(GiteaSignatureHeader), body, secret)
+}
+
+func (giteaAdapter) EventType(header http.Header, payload []byte) (string, error) {
+	eventType := header.Get(GiteaEventHeader)
+	if eventType == "" {
+		return "", fmt.Errorf("header %v can not empty", GiteaEventHeader)
+	}
+	return eventType, nil
+}
+
+func (giteaAdapter) DeliveryId(header http.Header) string {
+	return header.Get(GiteaDeliveryHeader)
+}
+
+func (giteaAdapter) ValuePaths() map[string][]string {
+	return githubValuePaths
+}
+
+type gitlabAdapter struct{}
+
+func (gitlabAdapter) Verify(header http.Header, body []byte, secret string) error {
+	if subtle.ConstantTimeCompare([]byte(header.Get(GitlabTokenHeader)), []byte(secret)) != 1 {
+		return fmt.Errorf("header %v not match", GitlabTokenHeader)
+	}
+	return nil
+}
+
+// EventType 优先使用 payload 中的 object_kind, 例如 push tag_push merge_request
+func (gitlabAdapter) EventType(header http.Header, payload []byte) (string, error) {
+	if kind := gjson.GetBytes(payload, "object_kind").String(); kind != "" {
+		return kind, nil
+	}
+
+	// Push Hook -> push
+	eventType := strings.ToLower(strings.TrimSuffix(header.Get(GitlabEventHeader), " Hook"))
+	if eventType == "" {
+		return "", fmt.Errorf("header %v can not empty", GitlabEventHeader)
+	}
+	return strings.ReplaceAll(eventType, " ", "_"), nil
+}
+
+func (gitlabAdapter) DeliveryId(header http.Header) string {
+	return header.Get(GitlabDeliveryHeader)
+}
+
+func (gitlabAdapter) ValuePaths() map[string][]string {
+	return map[string][]string{
+		"ref":          {"ref"},
+		"commit":       {"checkout_sha", "object_attributes.last_commit.id"},
+		"repository":   {"project.path_with_namespace"},
+		"sender":       {"user_username", "user.username"},
+		"action":       {"object_attributes.action"},
+		"number":       {"object_attributes.iid"},
+		"sourceBranch": {"object_attributes.source_branch"},
+		"targetBranch": {"object_attributes.target_branch"},
+	}
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"eventops/pkg/schema/event"
+	"net/http"
+	"testing"
+)
+
+func TestGithubAdapter(t *testing.T) {
+	var body = []byte(`{"ref": "refs/heads/master", "after": "abc", "repository": {"full_name": "kakj-go/eventops"}, "sender": {"login": "kakj"}}`)
+	var secret = "secret"
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	header := http.Header{}
+	header.Set(GithubEventHeader, "push")
+	header.Set(GithubSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	adapter, _ := GetAdapter(event.GithubWebhookProvider)
+	if err := adapter.Verify(header, body, secret); err != nil {
+		t.Fatalf("verify error: %v", err)
+	}
+	if err := adapter.Verify(header, body, "other"); err == nil {
+		t.Fatalf("verify with other secret should error")
+	}
+
+	eventType, err := adapter.EventType(header, body)
+	if err != nil || eventType != "push" {
+		t.Fatalf("event type %v error: %v", eventType, err)
+	}
+
+	values := BuildValues(adapter, body)
+	if values["branch"] != "master" || values["commit"] != "abc" || values["repository"] != "kakj-go/eventops" || values["sender"] != "kakj" {
+		t.Fatalf("values %v not match", values)
+	}
+	if _, ok := values["tag"]; ok {
+		t.Fatalf("values %v should not have tag", values)
+	}
+}
+
+func TestGitlabAdapter(t *testing.T) {
+	var body = []byte(`{"object_kind": "tag_push", "ref": "refs/tags/v1.0", "checkout_sha": "abc", "project": {"path_with_namespace": "kakj/eventops"}}`)
+
+	header := http.Header{}
+	header.Set(GitlabEventHeader, "Tag Push Hook")
+	header.Set(GitlabTokenHeader, "secret")
+
+	adapter, _ := GetAdapter(event.GitlabWebhookProvider)
+	if err := adapter.Verify(header, body, "secret"); err != nil {
+		t.Fatalf("verify error: %v", err)
+	}
+	if err := adapter.Verify(header, body, "other"); err == nil {
+		t.Fatalf("verify with other secret should error")
+	}
+
+	eventType, err := adapter.EventType(header, []byte(`{}`))
+	if err != nil || eventType != "tag_push" {
+		t.Fatalf("event type %v error: %v", eventType, err)
+	}
+
+	values := BuildValues(adapter, body)
+	if values["tag"] != "v1.0" || values["repository"] != "kakj/eventops" {
+		t.Fatalf("values %v not match", values)
+	}
+}
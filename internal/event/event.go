@@ -16,25 +16,28 @@ package event
 import (
 	"context"
 	"eventops/internal/core/client/eventclient"
+	"eventops/internal/core/client/webhookclient"
 	"eventops/internal/core/eventprocess"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type Service struct {
-	ctx           context.Context
-	eventDbClient *eventclient.Client
-	dbClient      *gorm.DB
+	ctx             context.Context
+	eventDbClient   *eventclient.Client
+	webhookDbClient *webhookclient.Client
+	dbClient        *gorm.DB
 
 	process *eventprocess.Process
 }
 
 func NewService(ctx context.Context, dbClient *gorm.DB, eventProcess *eventprocess.Process) *Service {
 	var register = Service{
-		ctx:           ctx,
-		eventDbClient: eventclient.NewEventClient(dbClient),
-		dbClient:      dbClient,
-		process:       eventProcess,
+		ctx:             ctx,
+		eventDbClient:   eventclient.NewEventClient(dbClient),
+		webhookDbClient: webhookclient.NewWebhookClient(dbClient),
+		dbClient:        dbClient,
+		process:         eventProcess,
 	}
 	return &register
 }
@@ -43,12 +46,14 @@ func (s *Service) Router(router *gin.RouterGroup) {
 	event := router.Group("/event")
 	{
 		event.POST("/send", s.send)
+		event.POST("/webhook/:provider/:name", s.webhook)
 	}
 }
 
 func (s *Service) Run() error {
 	s.process.ProcessEvent()
 	go s.process.LoopLoadProcessingEventToPass()
+	go s.process.LoopSchedule()
 	return s.process.LoadPassEventTriggerToProcess()
 }
 
@@ -16,14 +16,23 @@ package event
 import (
 	"encoding/json"
 	"eventops/apistructs"
+	"eventops/conf"
 	"eventops/internal/core/client/eventclient"
 	"eventops/internal/core/token"
 	"eventops/pkg/responsehandler"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"net/http"
+	"time"
 )
 
+// events.content 是 mediumtext, 最多保存 16M-1 字节
+const maxEventContentSize = 16<<20 - 1
+
+var eventContentTooLargeError = fmt.Errorf("event content size can not greater than %v", maxEventContentSize)
+
 func (s *Service) send(c *gin.Context) {
 	var eventInfo apistructs.Event
 	if err := c.ShouldBind(&eventInfo); err != nil {
@@ -35,26 +44,96 @@ func (s *Service) send(c *gin.Context) {
 		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("event check error: %v", err), nil))
 		return
 	}
-
-	eventInfoContent, err := json.Marshal(eventInfo)
-	if err != nil {
-		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("json Marshal error: %v", err), nil))
+	if apistructs.IsReservedEventName(eventInfo.Name) {
+		c.JSON(responsehandler.Build(http.StatusBadRequest, fmt.Sprintf("event name can not start with %v", apistructs.ReservedEventNamePrefix), nil))
 		return
 	}
+	// 串联深度只能由 server 发布的流水线结束事件设置
+	eventInfo.ChainDepth = 0
 
-	createEvent := eventclient.Event{
-		Name:    eventInfo.Name,
-		Version: eventInfo.Version,
-		Content: string(eventInfoContent),
-		Creater: token.GetUserName(c),
-		Status:  apistructs.EventCreatedStatus,
+	createEvent, duplicate, err := s.saveEvent(eventInfo, token.GetUserName(c))
+	if err == eventContentTooLargeError {
+		c.JSON(responsehandler.Build(http.StatusRequestEntityTooLarge, err.Error(), nil))
+		return
 	}
-	_, err = s.eventDbClient.CreateEvent(nil, &createEvent)
 	if err != nil {
 		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("save event error: %v", err), nil))
 		return
 	}
 
-	s.process.AddToProcess(createEvent)
-	c.JSON(responsehandler.Build(http.StatusOK, "", nil))
+	if !duplicate {
+		s.process.AddToProcess(*createEvent)
+	}
+	c.JSON(responsehandler.Build(http.StatusOK, "", apistructs.SendEventResponse{Id: createEvent.Id, Duplicate: duplicate}))
+}
+
+// saveEvent 找到重复的事件时返回之前保存的事件, idempotencyKey 相同的事件只会保存一次, 内容 hash 只在去重时间窗口内判断
+func (s *Service) saveEvent(eventInfo apistructs.Event, creater string) (*eventclient.Event, bool, error) {
+	eventInfoContent, err := json.Marshal(eventInfo)
+	if err != nil {
+		return nil, false, fmt.Errorf("json Marshal error: %v", err)
+	}
+	if len(eventInfoContent) > maxEventContentSize {
+		return nil, false, eventContentTooLargeError
+	}
+	contentHash, err := eventInfo.ContentHash()
+	if err != nil {
+		return nil, false, err
+	}
+
+	dedupConf := conf.GetEvent().Dedup
+	var query = eventclient.DuplicateEventQuery{
+		Name:           eventInfo.Name,
+		Creater:        creater,
+		IdempotencyKey: eventInfo.IdempotencyKey,
+	}
+	if dedupConf.ContentHash {
+		query.ContentHash = contentHash
+	}
+	// idempotencyKey 有唯一键, 不受时间窗口的限制
+	if dedupConf.Window > 0 && eventInfo.IdempotencyKey == "" {
+		query.CreatedAfter = &[]time.Time{time.Now().Add(-time.Duration(dedupConf.Window) * time.Second)}[0]
+	}
+
+	var result *eventclient.Event
+	var duplicate bool
+	err = s.dbClient.Transaction(func(tx *gorm.DB) error {
+		// 加锁读, 避免并发请求同时保存相同的事件
+		dbEvent, find, err := s.eventDbClient.GetDuplicateEvent(tx.Clauses(clause.Locking{Strength: "UPDATE"}), query)
+		if err != nil {
+			return err
+		}
+		if find {
+			result = dbEvent
+			duplicate = true
+			return nil
+		}
+
+		result = &eventclient.Event{
+			Name:           eventInfo.Name,
+			Version:        eventInfo.Version,
+			Content:        string(eventInfoContent),
+			Creater:        creater,
+			Status:         apistructs.EventCreatedStatus,
+			IdempotencyKey: eventInfo.IdempotencyKey,
+			ContentHash:    contentHash,
+		}
+		_, err = s.eventDbClient.CreateEvent(tx, result)
+		return err
+	})
+	if err != nil {
+		// 锁住不存在的行只会加间隙锁, 并发保存时会唯一键冲突或者死锁, 这时其他请求已经保存了事件
+		if !eventclient.IsConcurrentCreateError(err) {
+			return nil, false, err
+		}
+		dbEvent, find, getErr := s.eventDbClient.GetDuplicateEvent(nil, query)
+		if getErr != nil {
+			return nil, false, getErr
+		}
+		if !find {
+			return nil, false, err
+		}
+		return dbEvent, true, nil
+	}
+	return result, duplicate, nil
 }
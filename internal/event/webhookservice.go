@@ -0,0 +1,133 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package event
+
+import (
+	"eventops/apistructs"
+	"eventops/internal/core/webhook"
+	"eventops/pkg/responsehandler"
+	"eventops/pkg/schema/event"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/http"
+	"time"
+)
+
+// events.content 是 mediumtext(16M), payload 保存时还会包一层事件的 json, 所以 webhook 内容最大为 10M
+const maxWebhookBodySize = 10 << 20
+
+type WebhookUrlQuery struct {
+	Provider string `uri:"provider"`
+	Name     string `uri:"name"`
+}
+
+func (s *Service) webhook(c *gin.Context) {
+	var query WebhookUrlQuery
+	if err := c.ShouldBindUri(&query); err != nil {
+		c.JSON(responsehandler.Build(http.StatusBadRequest, fmt.Sprintf("failed to get provider and name from uri error: %v", err), nil))
+		return
+	}
+
+	dbWebhook, find, err := s.webhookDbClient.GetWebhook(nil, query.Name)
+	if err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("get webhook error: %v", err), nil))
+		return
+	}
+	if !find || dbWebhook.Provider != query.Provider {
+		c.JSON(responsehandler.Build(http.StatusNotFound, fmt.Sprintf("not find %v webhook %v", query.Provider, query.Name), nil))
+		return
+	}
+
+	var webhookInfo event.Webhook
+	if err := yaml.Unmarshal([]byte(dbWebhook.Content), &webhookInfo); err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("webhook content unmarshal error: %v", err), nil))
+		return
+	}
+
+	adapter, ok := webhook.GetAdapter(webhookInfo.Provider)
+	if !ok {
+		c.JSON(responsehandler.Build(http.StatusBadRequest, fmt.Sprintf("webhook provider %v not support", webhookInfo.Provider), nil))
+		return
+	}
+
+	// 多读一个字节判断内容是否超过限制, 截断的内容无法校验签名和解析
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBodySize+1))
+	if err != nil {
+		c.JSON(responsehandler.Build(http.StatusBadRequest, fmt.Sprintf("read body error: %v", err), nil))
+		return
+	}
+	if len(body) > maxWebhookBodySize {
+		c.JSON(responsehandler.Build(http.StatusRequestEntityTooLarge, fmt.Sprintf("body size can not greater than %v", maxWebhookBodySize), nil))
+		return
+	}
+
+	if err := adapter.Verify(c.Request.Header, body, webhookInfo.Secret); err != nil {
+		logrus.Warnf("[webhook] %v webhook %v verify error: %v", query.Provider, query.Name, err)
+		c.JSON(responsehandler.Build(http.StatusUnauthorized, fmt.Sprintf("verify error: %v", err), nil))
+		return
+	}
+
+	payload, err := webhook.GetPayload(c.Request.Header, body)
+	if err != nil {
+		c.JSON(responsehandler.Build(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+
+	eventType, err := adapter.EventType(c.Request.Header, payload)
+	if err != nil {
+		c.JSON(responsehandler.Build(http.StatusBadRequest, err.Error(), nil))
+		return
+	}
+	if !webhookInfo.AcceptEvent(eventType) {
+		c.JSON(responsehandler.Build(http.StatusOK, fmt.Sprintf("event %v ignored", eventType), nil))
+		return
+	}
+
+	var eventInfo = apistructs.Event{
+		Name:         webhookInfo.BuildEventName(eventType),
+		Version:      webhookInfo.EventVersion,
+		Values:       webhook.BuildValues(adapter, payload),
+		Timestamp:    time.Now().Unix(),
+		SupportUsers: webhookInfo.Users,
+		Payload:      payload,
+		// 重新投递的请求 delivery id 不变, 可以用来去重
+		IdempotencyKey: adapter.DeliveryId(c.Request.Header),
+	}
+	if err := eventInfo.Check(); err != nil {
+		c.JSON(responsehandler.Build(http.StatusBadRequest, fmt.Sprintf("event check error: %v", err), nil))
+		return
+	}
+	if apistructs.IsReservedEventName(eventInfo.Name) {
+		c.JSON(responsehandler.Build(http.StatusBadRequest, fmt.Sprintf("event name can not start with %v", apistructs.ReservedEventNamePrefix), nil))
+		return
+	}
+
+	createEvent, duplicate, err := s.saveEvent(eventInfo, dbWebhook.Creater)
+	if err == eventContentTooLargeError {
+		c.JSON(responsehandler.Build(http.StatusRequestEntityTooLarge, err.Error(), nil))
+		return
+	}
+	if err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("save event error: %v", err), nil))
+		return
+	}
+
+	if !duplicate {
+		s.process.AddToProcess(*createEvent)
+	}
+	c.JSON(responsehandler.Build(http.StatusOK, "", apistructs.SendEventResponse{Id: createEvent.Id, Duplicate: duplicate}))
+}
@@ -61,6 +61,13 @@ func (s *Service) Router(router *gin.RouterGroup) {
 	clientGroup := router.Group("/pipeline")
 	{
 		clientGroup.POST("/:id/cancel", s.Cancel)
+		clientGroup.POST("/:id/clean", s.Clean)
+		clientGroup.POST("/:id/rerun", s.Rerun)
+		clientGroup.POST("/:id/pause", s.Pause)
+		clientGroup.POST("/:id/resume", s.Resume)
+		clientGroup.GET("/:id/task/:taskId/logs", s.Logs)
+		clientGroup.POST("/:id/task/:taskId/approve", s.Approve)
+		clientGroup.POST("/:id/task/:taskId/reject", s.Reject)
 		clientGroup.GET("/:id", s.Get)
 		clientGroup.GET("/", s.List)
 		clientGroup.POST("/callback", s.Callback)
@@ -68,6 +75,7 @@ func (s *Service) Router(router *gin.RouterGroup) {
 }
 
 func (s *Service) Run() error {
+	go s.manager.LoopGc()
 	return s.manager.Run()
 }
 
@@ -16,6 +16,7 @@ package pipeline
 import (
 	"eventops/apistructs"
 	"eventops/internal/core/client/pipelineclient"
+	"eventops/internal/core/client/taskclient"
 	"eventops/internal/core/token"
 	"eventops/pkg/limit_sync_group"
 	"eventops/pkg/responsehandler"
@@ -39,7 +40,27 @@ func (s *Service) Cancel(c *gin.Context) {
 
 	flow := s.manager.GetFlow(cancel.Id)
 	if flow == nil {
-		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("not find this runtime: %v", cancel.Id), nil))
+		// 排队的流水线还没有运行, 直接修改状态
+		dbPipeline, find, err := s.pipelineDbClient.GetPipeline(nil, cancel.Id, token.GetUserName(c))
+		if err != nil {
+			c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to get pipeline runtime: %v error: %v", cancel.Id, err), nil))
+			return
+		}
+		if !find || (!dbPipeline.Status.IsQueued() && !dbPipeline.Status.IsPaused()) {
+			c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("not find this runtime: %v", cancel.Id), nil))
+			return
+		}
+
+		cancelFunc := s.manager.CancelQueuedPipeline
+		if dbPipeline.Status.IsPaused() {
+			// 服务重启后暂停的流水线不在内存中
+			cancelFunc = s.manager.CancelPausedPipeline
+		}
+		if err := cancelFunc(cancel.Id, token.GetUserName(c)); err != nil {
+			c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to cancel pipeline runtime: %v error: %v", cancel.Id, err), nil))
+			return
+		}
+		c.JSON(responsehandler.Build(http.StatusOK, "", fmt.Sprintf("pipeline runtime cancel success")))
 		return
 	}
 
@@ -65,6 +86,203 @@ func (s *Service) Cancel(c *gin.Context) {
 	c.JSON(responsehandler.Build(http.StatusOK, "", fmt.Sprintf("pipeline runtime cancel %v", status)))
 }
 
+type CleanPipelineQuery struct {
+	Id uint64 `uri:"id"`
+}
+
+func (s *Service) Clean(c *gin.Context) {
+	var clean CleanPipelineQuery
+	if err := c.ShouldBindUri(&clean); err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to clean pipeline runtime: %v error: %v", clean.Id, err), nil))
+		return
+	}
+
+	dbPipeline, find, err := s.pipelineDbClient.GetPipeline(nil, clean.Id, token.GetUserName(c))
+	if err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to get pipeline runtime: %v error: %v", clean.Id, err), nil))
+		return
+	}
+	if !find {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("not find this runtime: %v", clean.Id), nil))
+		return
+	}
+
+	err = s.manager.CleanPipeline(dbPipeline)
+	if err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to clean pipeline runtime: %v error: %v", clean.Id, err), nil))
+		return
+	}
+
+	c.JSON(responsehandler.Build(http.StatusOK, "", fmt.Sprintf("pipeline runtime clean success")))
+}
+
+type RerunPipelineQuery struct {
+	Id uint64 `uri:"id"`
+}
+
+func (s *Service) Rerun(c *gin.Context) {
+	var rerun RerunPipelineQuery
+	if err := c.ShouldBindUri(&rerun); err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to rerun pipeline runtime: %v error: %v", rerun.Id, err), nil))
+		return
+	}
+
+	var body apistructs.RerunPipelineBody
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to bind rerun body error: %v", err), nil))
+			return
+		}
+	}
+
+	dbPipeline, find, err := s.pipelineDbClient.GetPipeline(nil, rerun.Id, token.GetUserName(c))
+	if err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to get pipeline runtime: %v error: %v", rerun.Id, err), nil))
+		return
+	}
+	if !find {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("not find this runtime: %v", rerun.Id), nil))
+		return
+	}
+
+	newPipeline, err := s.manager.RerunPipeline(dbPipeline, body.FromFailed)
+	if err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to rerun pipeline runtime: %v error: %v", rerun.Id, err), nil))
+		return
+	}
+
+	c.JSON(responsehandler.Build(http.StatusOK, "", newPipeline.ToApiStruct()))
+}
+
+type TaskLogsQuery struct {
+	Id     uint64 `uri:"id"`
+	TaskId uint64 `uri:"taskId"`
+}
+
+func (s *Service) Logs(c *gin.Context) {
+	var query TaskLogsQuery
+	if err := c.ShouldBindUri(&query); err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to get pipeline runtime task logs error: %v", err), nil))
+		return
+	}
+	follow, _ := strconv.ParseBool(c.Query("follow"))
+
+	dbTasks, err := s.taskDbClient.ListTasks(nil, query.Id, token.GetUserName(c))
+	if err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to list pipeline runtime: %v tasks error: %v", query.Id, err), nil))
+		return
+	}
+	var dbTask *taskclient.Task
+	for _, task := range dbTasks {
+		if task.Id == query.TaskId {
+			dbTask = task
+			break
+		}
+	}
+	if dbTask == nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("not find runtime: %v task: %v", query.Id, query.TaskId), nil))
+		return
+	}
+
+	reader, err := s.manager.TaskLogs(c.Request.Context(), dbTask, follow)
+	if err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to get runtime: %v task: %v logs error: %v", query.Id, query.TaskId, err), nil))
+		return
+	}
+	defer reader.Close()
+
+	// 日志以 chunked 的方式持续输出
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+	var chunk = make([]byte, 4096)
+	for {
+		n, err := reader.Read(chunk)
+		if n > 0 {
+			if _, writeErr := c.Writer.Write(chunk[:n]); writeErr != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+type ApproveTaskQuery struct {
+	Id     uint64 `uri:"id"`
+	TaskId uint64 `uri:"taskId"`
+}
+
+func (s *Service) Approve(c *gin.Context) {
+	s.approveTask(c, true)
+}
+
+func (s *Service) Reject(c *gin.Context) {
+	s.approveTask(c, false)
+}
+
+func (s *Service) approveTask(c *gin.Context, approved bool) {
+	var query ApproveTaskQuery
+	if err := c.ShouldBindUri(&query); err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to approve pipeline runtime task error: %v", err), nil))
+		return
+	}
+
+	var body apistructs.ApproveTaskBody
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to bind approve body error: %v", err), nil))
+			return
+		}
+	}
+
+	err := s.manager.ApproveTask(query.Id, query.TaskId, token.GetUserName(c), approved, body.Comment)
+	if err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to approve pipeline runtime: %v task: %v error: %v", query.Id, query.TaskId, err), nil))
+		return
+	}
+
+	var result = apistructs.ApprovedResult
+	if !approved {
+		result = apistructs.RejectedResult
+	}
+	c.JSON(responsehandler.Build(http.StatusOK, "", fmt.Sprintf("pipeline runtime task %v", result)))
+}
+
+type PausePipelineQuery struct {
+	Id uint64 `uri:"id"`
+}
+
+func (s *Service) Pause(c *gin.Context) {
+	var pause PausePipelineQuery
+	if err := c.ShouldBindUri(&pause); err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to pause pipeline runtime: %v error: %v", pause.Id, err), nil))
+		return
+	}
+
+	if err := s.manager.PauseFlow(pause.Id, token.GetUserName(c)); err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to pause pipeline runtime: %v error: %v", pause.Id, err), nil))
+		return
+	}
+	c.JSON(responsehandler.Build(http.StatusOK, "", fmt.Sprintf("pipeline runtime pause success")))
+}
+
+func (s *Service) Resume(c *gin.Context) {
+	var resume PausePipelineQuery
+	if err := c.ShouldBindUri(&resume); err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to resume pipeline runtime: %v error: %v", resume.Id, err), nil))
+		return
+	}
+
+	if err := s.manager.ResumeFlow(resume.Id, token.GetUserName(c)); err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to resume pipeline runtime: %v error: %v", resume.Id, err), nil))
+		return
+	}
+	c.JSON(responsehandler.Build(http.StatusOK, "", fmt.Sprintf("pipeline runtime resume success")))
+}
+
 type GetPipelineQuery struct {
 	Id uint64 `uri:"id"`
 }
@@ -121,6 +339,15 @@ func (s *Service) Get(c *gin.Context) {
 		return
 	}
 
+	if pipelineDetail.Pipeline.Status.IsQueued() {
+		schedule, err := s.manager.GetPipelineSchedule(get.Id)
+		if err != nil {
+			c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to get pipeline runtime schedule error: %v", err), nil))
+			return
+		}
+		pipelineDetail.Schedule = schedule
+	}
+
 	c.JSON(responsehandler.Build(http.StatusOK, "", pipelineDetail))
 }
 
@@ -135,6 +362,9 @@ type pipelineListQuery struct {
 	PipelineDefinitionVersion string
 	PipelineDefinitionCreater string
 
+	Status           string
+	ConcurrencyGroup string
+
 	Top uint64
 }
 
@@ -147,6 +377,8 @@ func (s *Service) List(c *gin.Context) {
 	body.PipelineDefinitionName = c.Query("pipelineDefinitionName")
 	body.PipelineDefinitionVersion = c.Query("pipelineDefinitionVersion")
 	body.PipelineDefinitionCreater = c.Query("pipelineDefinitionCreater")
+	body.Status = c.Query("status")
+	body.ConcurrencyGroup = c.Query("concurrencyGroup")
 	top := c.Query("top")
 	if len(top) > 0 {
 		topInt, err := strconv.ParseUint(c.Query("top"), 10, 64)
@@ -167,6 +399,10 @@ func (s *Service) List(c *gin.Context) {
 	var query pipelineclient.ListPipelineQuery
 	query.Creater = token.GetUserName(c)
 	query.Top = body.Top
+	query.ConcurrencyGroup = body.ConcurrencyGroup
+	if body.Status != "" {
+		query.Statuses = []apistructs.PipelineStatus{apistructs.PipelineStatus(body.Status)}
+	}
 
 	worker := limit_sync_group.NewWorker(3)
 	if body.EventName != "" && body.EventVersion != "" && body.EventCreater != "" {
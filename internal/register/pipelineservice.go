@@ -15,6 +15,7 @@ package register
 
 import (
 	"eventops/apistructs"
+	"eventops/conf"
 	"eventops/internal/core/client/pipelinedefinitionclient"
 	"eventops/internal/core/token"
 	"eventops/pkg/responsehandler"
@@ -228,10 +229,14 @@ func (r *Service) applyPipelineMutatingAndCheck(c *gin.Context, content string)
 		return nil, "", err
 	}
 
-	if err := pipeInfo.Mutating(taskImagePipelineDefinitionMap); err != nil {
+	if err := pipeInfo.Mutating(taskImagePipelineDefinitionMap, conf.GetPipeline().Timeout.TaskDefault); err != nil {
 		return nil, "", fmt.Errorf("pipeline Mutating fieled error: %v", err)
 	}
 
+	if maxTimeout := conf.GetPipeline().Timeout.Max; maxTimeout > 0 && pipeInfo.Timeout > maxTimeout {
+		return nil, "", fmt.Errorf("pipeline timeout can not greater than %v", maxTimeout)
+	}
+
 	yamlContent, err := yaml.Marshal(pipeInfo)
 	if err != nil {
 		return nil, "", fmt.Errorf("pipeline yaml content marshal error: %v", err)
@@ -18,6 +18,7 @@ import (
 	"eventops/internal/core/client/actuatorclient"
 	"eventops/internal/core/client/pipelinedefinitionclient"
 	"eventops/internal/core/client/triggerdefinitionclient"
+	"eventops/internal/core/client/webhookclient"
 	"eventops/internal/core/dialer"
 	"eventops/internal/core/eventprocess"
 	"github.com/gin-gonic/gin"
@@ -28,6 +29,7 @@ func NewService(ctx context.Context, dbClient *gorm.DB, eventProcess *eventproce
 	pipelineVersionDefinitionClient := pipelinedefinitionclient.NewPipelineDefinitionClient(dbClient)
 	triggerDefinitionClient := triggerdefinitionclient.NewTriggerDefinitionClient(dbClient)
 	actuatorClient := actuatorclient.NewActuatorsClient(dbClient)
+	webhookClient := webhookclient.NewWebhookClient(dbClient)
 
 	var register = Service{
 		ctx:      ctx,
@@ -36,6 +38,7 @@ func NewService(ctx context.Context, dbClient *gorm.DB, eventProcess *eventproce
 		pipelineVersionDefinitionClient: pipelineVersionDefinitionClient,
 		triggerDefinitionClient:         triggerDefinitionClient,
 		actuatorClient:                  actuatorClient,
+		webhookClient:                   webhookClient,
 
 		dialerServer: dialerServer,
 		eventProcess: eventProcess,
@@ -50,6 +53,7 @@ type Service struct {
 	pipelineVersionDefinitionClient *pipelinedefinitionclient.Client
 	triggerDefinitionClient         *triggerdefinitionclient.Client
 	actuatorClient                  *actuatorclient.Client
+	webhookClient                   *webhookclient.Client
 
 	dbClient *gorm.DB
 	ctx      context.Context
@@ -79,6 +83,13 @@ func (r *Service) Router(router *gin.RouterGroup) {
 		clientGroup.DELETE("/:name", r.DeleteActuator)
 		clientGroup.GET("/", r.ListMyActuator)
 	}
+
+	webhookGroup := router.Group("/webhook")
+	{
+		webhookGroup.POST("/apply", r.ApplyWebhook)
+		webhookGroup.DELETE("/:name", r.DeleteWebhook)
+		webhookGroup.GET("/", r.ListMyWebhook)
+	}
 }
 
 func (r *Service) Run() error {
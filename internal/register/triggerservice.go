@@ -15,6 +15,7 @@ package register
 
 import (
 	"eventops/apistructs"
+	"eventops/conf"
 	"eventops/internal/core/client/triggerdefinitionclient"
 	"eventops/internal/core/token"
 	"eventops/pkg/responsehandler"
@@ -23,6 +24,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"gopkg.in/yaml.v3"
 	"net/http"
+	"time"
 )
 
 func (s *Service) ListMyTriggerDefinition(c *gin.Context) {
@@ -91,6 +93,15 @@ func (s *Service) ApplyTriggerDefinition(c *gin.Context) {
 		return
 	}
 
+	if maxTimeout := conf.GetPipeline().Timeout.Max; maxTimeout > 0 {
+		for _, pipe := range trigger.Pipelines {
+			if pipe.Timeout > maxTimeout {
+				c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("trigger definition pipeline %v timeout can not greater than %v", pipe.Image, maxTimeout), nil))
+				return
+			}
+		}
+	}
+
 	newContent, err := yaml.Marshal(trigger)
 	if err != nil {
 		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, err.Error(), nil))
@@ -103,6 +114,12 @@ func (s *Service) ApplyTriggerDefinition(c *gin.Context) {
 		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("get event trigger definition error: %v", err), nil))
 		return
 	}
+	var schedule string
+	if trigger.Schedule != nil {
+		schedule = trigger.Schedule.Cron
+	}
+	var now = time.Now().Truncate(time.Second)
+
 	if !find {
 		var createTrigger = triggerdefinitionclient.EventTriggerDefinition{
 			Name:         trigger.Name,
@@ -111,6 +128,10 @@ func (s *Service) ApplyTriggerDefinition(c *gin.Context) {
 			EventName:    trigger.EventName,
 			EventCreater: trigger.EventCreater,
 			EventVersion: trigger.EventVersion,
+			Schedule:     schedule,
+		}
+		if schedule != "" {
+			createTrigger.ScheduleLastTime = &now
 		}
 		_, err := s.triggerDefinitionClient.CreateEventTriggerDefinition(nil, &createTrigger)
 		if err != nil {
@@ -118,16 +139,32 @@ func (s *Service) ApplyTriggerDefinition(c *gin.Context) {
 			return
 		}
 	} else {
+		// 修改了监听的事件后, 之前事件的缓存中还有这个触发器
+		oldCacheKey := s.eventProcess.MakeCacheKey(triggerDefinition.EventName, triggerDefinition.EventVersion, triggerDefinition.EventCreater)
+
 		triggerDefinition.EventName = trigger.EventName
 		triggerDefinition.EventCreater = trigger.EventCreater
 		triggerDefinition.EventVersion = trigger.EventVersion
 		triggerDefinition.Content = applyInfo.TriggerContent
 
+		var scheduleChanged = triggerDefinition.Schedule != schedule
+		triggerDefinition.Schedule = schedule
+
 		_, err := s.triggerDefinitionClient.UpdateEventTriggerDefinition(nil, triggerDefinition)
 		if err != nil {
 			c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("update event trigger definition error: %v", err), nil))
 			return
 		}
+		s.eventProcess.DeleteTriggerCache(oldCacheKey)
+
+		// cron 修改后从现在开始计算, 不去补之前错过的触发
+		if scheduleChanged && schedule != "" {
+			_, err := s.triggerDefinitionClient.UpdateScheduleLastTime(nil, triggerDefinition.Id, triggerDefinition.ScheduleLastTime, now)
+			if err != nil {
+				c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("update event trigger definition schedule error: %v", err), nil))
+				return
+			}
+		}
 	}
 	s.eventProcess.DeleteTriggerCache(s.eventProcess.MakeCacheKey(trigger.EventName, trigger.EventVersion, trigger.EventCreater))
 
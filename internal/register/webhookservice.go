@@ -0,0 +1,137 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package register
+
+import (
+	"eventops/apistructs"
+	"eventops/internal/core/client/webhookclient"
+	"eventops/internal/core/token"
+	"eventops/pkg/responsehandler"
+	"eventops/pkg/schema/event"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+	"net/http"
+)
+
+type ApplyWebhookRequest struct {
+	WebhookContent string `json:"webhookContent"`
+}
+
+func (r *Service) ApplyWebhook(c *gin.Context) {
+	var applyInfo ApplyWebhookRequest
+	if err := c.ShouldBind(&applyInfo); err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, err.Error(), nil))
+		return
+	}
+
+	var webhookInfo event.Webhook
+	err := yaml.Unmarshal([]byte(applyInfo.WebhookContent), &webhookInfo)
+	if err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("yaml content unmarshal error: %v", err), nil))
+		return
+	}
+
+	if err := webhookInfo.Mutating(); err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, err.Error(), nil))
+		return
+	}
+
+	if err := webhookInfo.Check(); err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, err.Error(), nil))
+		return
+	}
+
+	newContent, err := yaml.Marshal(webhookInfo)
+	if err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, err.Error(), nil))
+		return
+	}
+
+	dbWebhook, find, err := r.webhookClient.GetWebhook(nil, webhookInfo.Name)
+	if err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("get webhook error: %v", err), nil))
+		return
+	}
+	if !find {
+		var createWebhook = webhookclient.Webhook{
+			Name:     webhookInfo.Name,
+			Creater:  token.GetUserName(c),
+			Provider: string(webhookInfo.Provider),
+			Content:  string(newContent),
+		}
+		if _, err := r.webhookClient.CreateWebhook(nil, &createWebhook); err != nil {
+			if err == webhookclient.NameConflictError {
+				c.JSON(responsehandler.Build(http.StatusConflict, fmt.Sprintf("webhook name %v already used", webhookInfo.Name), nil))
+				return
+			}
+			c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("create webhook error: %v", err), nil))
+			return
+		}
+	} else {
+		if dbWebhook.Creater != token.GetUserName(c) {
+			c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("webhook name %v already used by other user", webhookInfo.Name), nil))
+			return
+		}
+
+		dbWebhook.Provider = string(webhookInfo.Provider)
+		dbWebhook.Content = string(newContent)
+		if _, err := r.webhookClient.UpdateWebhook(nil, dbWebhook); err != nil {
+			c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("update webhook error: %v", err), nil))
+			return
+		}
+	}
+
+	c.JSON(responsehandler.Build(http.StatusOK, "", fmt.Sprintf("/api/event/webhook/%v/%v", webhookInfo.Provider, webhookInfo.Name)))
+}
+
+func (r *Service) DeleteWebhook(c *gin.Context) {
+	var deleteQuery = DeleteNameUrlQuery{}
+	if err := c.ShouldBindUri(&deleteQuery); err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to get name from uri error: %v", err), nil))
+		return
+	}
+
+	dbWebhook, find, err := r.webhookClient.GetWebhook(nil, deleteQuery.Name)
+	if err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("get webhook error: %v", err), nil))
+		return
+	}
+	if !find || dbWebhook.Creater != token.GetUserName(c) {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("not find webhook"), nil))
+		return
+	}
+
+	if err := r.webhookClient.DeleteWebhook(nil, dbWebhook.Name, token.GetUserName(c)); err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("delete webhook error: %v", err), nil))
+		return
+	}
+
+	c.JSON(responsehandler.Build(http.StatusOK, "", nil))
+}
+
+func (r *Service) ListMyWebhook(c *gin.Context) {
+	dbWebhooks, err := r.webhookClient.ListWebhook(nil, token.GetUserName(c))
+	if err != nil {
+		c.JSON(responsehandler.Build(http.StatusServiceUnavailable, fmt.Sprintf("failed to list webhook error: %v", err), nil))
+		return
+	}
+
+	var result []apistructs.Webhook
+	for _, dbWebhook := range dbWebhooks {
+		result = append(result, dbWebhook.ToApiStructs())
+	}
+
+	c.JSON(responsehandler.Build(http.StatusOK, "", result))
+}
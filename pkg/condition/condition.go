@@ -0,0 +1,248 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package condition
+
+import (
+	"eventops/pkg/placeholder"
+	"fmt"
+	"strings"
+)
+
+// 支持的表达式:
+//   ${{ inputs.env }} == 'prod' && (${{ outputs.build.result }} != 'skip' || !${{ contexts.force }})
+// 操作数可以是占位符、单引号或双引号字符串、以及不带引号的单词(true, false, 数字等)
+
+type tokenType int
+
+const (
+	valueToken tokenType = iota
+	placeholderToken
+	eqToken
+	neToken
+	andToken
+	orToken
+	notToken
+	leftParenToken
+	rightParenToken
+)
+
+type token struct {
+	typ   tokenType
+	value string
+}
+
+type Resolver func(placeholder string) string
+
+func Check(expr string) error {
+	_, err := Eval(expr, func(placeholder string) string {
+		return ""
+	})
+	return err
+}
+
+func Eval(expr string, resolver Resolver) (bool, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return false, err
+	}
+	if len(tokens) == 0 {
+		return false, fmt.Errorf("condition %v can not empty", expr)
+	}
+
+	p := &parser{tokens: tokens, resolver: resolver}
+	result, err := p.parseOr()
+	if err != nil {
+		return false, fmt.Errorf("condition %v error: %v", expr, err)
+	}
+	if p.pos != len(p.tokens) {
+		return false, fmt.Errorf("condition %v error: unexpected %v", expr, p.tokens[p.pos].value)
+	}
+	return isTrue(result), nil
+}
+
+func lex(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(expr[i:], placeholder.Left):
+			end := strings.Index(expr[i:], placeholder.Right)
+			if end < 0 {
+				return nil, fmt.Errorf("condition %v placeholder not closed", expr)
+			}
+			value := expr[i : i+end+len(placeholder.Right)]
+			if !placeholder.PhRe.MatchString(value) {
+				return nil, fmt.Errorf("condition %v placeholder %v format error", expr, value)
+			}
+			tokens = append(tokens, token{typ: placeholderToken, value: value})
+			i = i + end + len(placeholder.Right)
+		case strings.HasPrefix(expr[i:], "=="):
+			tokens = append(tokens, token{typ: eqToken, value: "=="})
+			i += 2
+		case strings.HasPrefix(expr[i:], "!="):
+			tokens = append(tokens, token{typ: neToken, value: "!="})
+			i += 2
+		case strings.HasPrefix(expr[i:], "&&"):
+			tokens = append(tokens, token{typ: andToken, value: "&&"})
+			i += 2
+		case strings.HasPrefix(expr[i:], "||"):
+			tokens = append(tokens, token{typ: orToken, value: "||"})
+			i += 2
+		case c == '!':
+			tokens = append(tokens, token{typ: notToken, value: "!"})
+			i++
+		case c == '(':
+			tokens = append(tokens, token{typ: leftParenToken, value: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{typ: rightParenToken, value: ")"})
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("condition %v string not closed", expr)
+			}
+			tokens = append(tokens, token{typ: valueToken, value: expr[i+1 : i+1+end]})
+			i = i + end + 2
+		default:
+			start := i
+			for i < len(expr) && !strings.ContainsRune(" \t\r\n=!&|()'\"$", rune(expr[i])) {
+				i++
+			}
+			if start == i {
+				return nil, fmt.Errorf("condition %v unexpected character %c", expr, c)
+			}
+			tokens = append(tokens, token{typ: valueToken, value: expr[start:i]})
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens   []token
+	pos      int
+	resolver Resolver
+}
+
+func (p *parser) peek() *token {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+func (p *parser) parseOr() (string, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return "", err
+	}
+	for p.peek() != nil && p.peek().typ == orToken {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return "", err
+		}
+		left = boolString(isTrue(left) || isTrue(right))
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (string, error) {
+	left, err := p.parseCompare()
+	if err != nil {
+		return "", err
+	}
+	for p.peek() != nil && p.peek().typ == andToken {
+		p.pos++
+		right, err := p.parseCompare()
+		if err != nil {
+			return "", err
+		}
+		left = boolString(isTrue(left) && isTrue(right))
+	}
+	return left, nil
+}
+
+func (p *parser) parseCompare() (string, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return "", err
+	}
+	next := p.peek()
+	if next == nil || (next.typ != eqToken && next.typ != neToken) {
+		return left, nil
+	}
+	p.pos++
+	right, err := p.parseUnary()
+	if err != nil {
+		return "", err
+	}
+	if next.typ == eqToken {
+		return boolString(left == right), nil
+	}
+	return boolString(left != right), nil
+}
+
+func (p *parser) parseUnary() (string, error) {
+	next := p.peek()
+	if next == nil {
+		return "", fmt.Errorf("unexpected end")
+	}
+
+	switch next.typ {
+	case notToken:
+		p.pos++
+		value, err := p.parseUnary()
+		if err != nil {
+			return "", err
+		}
+		return boolString(!isTrue(value)), nil
+	case leftParenToken:
+		p.pos++
+		value, err := p.parseOr()
+		if err != nil {
+			return "", err
+		}
+		if p.peek() == nil || p.peek().typ != rightParenToken {
+			return "", fmt.Errorf("missing )")
+		}
+		p.pos++
+		return value, nil
+	case placeholderToken:
+		p.pos++
+		return p.resolver(next.value), nil
+	case valueToken:
+		p.pos++
+		return next.value, nil
+	default:
+		return "", fmt.Errorf("unexpected %v", next.value)
+	}
+}
+
+func boolString(value bool) string {
+	if value {
+		return "true"
+	}
+	return "false"
+}
+
+func isTrue(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "false", "0", "no":
+		return false
+	}
+	return true
+}
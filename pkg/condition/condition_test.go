@@ -0,0 +1,68 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package condition
+
+import (
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	var values = map[string]string{
+		"${{ inputs.env }}":           "prod",
+		"${{ outputs.build.result }}": "ok",
+		"${{ contexts.force }}":       "false",
+	}
+	var resolver = func(placeholder string) string {
+		return values[placeholder]
+	}
+
+	var cases = []struct {
+		expr   string
+		result bool
+	}{
+		{"${{ inputs.env }} == 'prod'", true},
+		{"${{ inputs.env }} != \"prod\"", false},
+		{"${{ inputs.env }} == prod && ${{ outputs.build.result }} == 'ok'", true},
+		{"${{ inputs.env }} == 'dev' || ${{ contexts.force }}", false},
+		{"!${{ contexts.force }}", true},
+		{"(${{ inputs.env }} == 'dev' || ${{ inputs.env }} == 'prod') && !(${{ outputs.build.result }} == 'fail')", true},
+		{"${{ inputs.missing }}", false},
+		{"true", true},
+	}
+	for _, c := range cases {
+		result, err := Eval(c.expr, resolver)
+		if err != nil {
+			t.Fatalf("expr %v error: %v", c.expr, err)
+		}
+		if result != c.result {
+			t.Fatalf("expr %v result %v, want %v", c.expr, result, c.result)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var errorExprs = []string{
+		"",
+		"${{ inputs.env }} ==",
+		"(${{ inputs.env }} == 'prod'",
+		"${{ inputs.env }} == 'prod",
+		"${{ inputs.env == 'prod'",
+		"${{ inputs.env }} 'prod'",
+	}
+	for _, expr := range errorExprs {
+		if err := Check(expr); err == nil {
+			t.Fatalf("expr %v should check error", expr)
+		}
+	}
+}
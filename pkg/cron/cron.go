@@ -0,0 +1,224 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 支持标准的 5 段 cron 表达式: 分 时 日 月 周
+// 每段支持 * , - / 以及月份和星期的英文缩写, 另外支持 @yearly @monthly @weekly @daily @hourly
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+type bounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	minuteBounds = bounds{0, 59, nil}
+	hourBounds   = bounds{0, 23, nil}
+	domBounds    = bounds{1, 31, nil}
+	monthBounds  = bounds{1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowBounds = bounds{0, 7, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if value, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = value
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %v should have 5 fields", spec)
+	}
+
+	var schedule Schedule
+	var err error
+	if schedule.minute, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, fmt.Errorf("cron %v minute error: %v", spec, err)
+	}
+	if schedule.hour, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, fmt.Errorf("cron %v hour error: %v", spec, err)
+	}
+	if schedule.dom, err = parseField(fields[2], domBounds); err != nil {
+		return nil, fmt.Errorf("cron %v day of month error: %v", spec, err)
+	}
+	if schedule.month, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, fmt.Errorf("cron %v month error: %v", spec, err)
+	}
+	if schedule.dow, err = parseField(fields[4], dowBounds); err != nil {
+		return nil, fmt.Errorf("cron %v day of week error: %v", spec, err)
+	}
+	// 7 和 0 都代表周日
+	if schedule.dow&(1<<7) > 0 {
+		schedule.dow |= 1
+	}
+	schedule.domStar = strings.HasPrefix(fields[2], "*")
+	schedule.dowStar = strings.HasPrefix(fields[4], "*")
+	return &schedule, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		value, err := parseRange(part, b)
+		if err != nil {
+			return 0, err
+		}
+		bits |= value
+	}
+	return bits, nil
+}
+
+func parseRange(part string, b bounds) (uint64, error) {
+	var rangeAndStep = strings.Split(part, "/")
+	if len(rangeAndStep) > 2 {
+		return 0, fmt.Errorf("%v format error", part)
+	}
+
+	var start, end uint
+	var lowAndHigh = strings.Split(rangeAndStep[0], "-")
+	switch {
+	case rangeAndStep[0] == "*":
+		start, end = b.min, b.max
+	case len(lowAndHigh) == 1:
+		value, err := parseValue(lowAndHigh[0], b)
+		if err != nil {
+			return 0, err
+		}
+		start, end = value, value
+	case len(lowAndHigh) == 2:
+		var err error
+		if start, err = parseValue(lowAndHigh[0], b); err != nil {
+			return 0, err
+		}
+		if end, err = parseValue(lowAndHigh[1], b); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("%v format error", part)
+	}
+
+	var step uint = 1
+	if len(rangeAndStep) == 2 {
+		value, err := strconv.ParseUint(rangeAndStep[1], 10, 32)
+		if err != nil || value == 0 {
+			return 0, fmt.Errorf("%v step error", part)
+		}
+		step = uint(value)
+		// 5/10 代表 5-max/10
+		if len(lowAndHigh) == 1 && rangeAndStep[0] != "*" {
+			end = b.max
+		}
+	}
+
+	if start > end {
+		return 0, fmt.Errorf("%v start greater than end", part)
+	}
+
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << i
+	}
+	return bits, nil
+}
+
+func parseValue(value string, b bounds) (uint, error) {
+	if number, ok := b.names[strings.ToLower(value)]; ok {
+		return number, nil
+	}
+	number, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%v not number", value)
+	}
+	if uint(number) < b.min || uint(number) > b.max {
+		return 0, fmt.Errorf("%v out of range [%v, %v]", value, b.min, b.max)
+	}
+	return uint(number), nil
+}
+
+// Next 返回 t 之后的下一次触发时间, 使用 t 的时区计算, 找不到的时候返回零值
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	yearLimit := t.Year() + 5
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for s.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+	return t
+}
+
+// 日和周都声明的时候满足其中一个即可, 和标准 cron 保持一致
+func (s *Schedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) > 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) > 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("load location error: %v", err)
+	}
+
+	var cases = []struct {
+		spec string
+		from time.Time
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2022, 9, 1, 10, 0, 30, 0, time.UTC), time.Date(2022, 9, 1, 10, 1, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2022, 9, 1, 2, 0, 0, 0, shanghai), time.Date(2022, 9, 2, 2, 0, 0, 0, shanghai)},
+		{"*/15 9-10 * * mon-fri", time.Date(2022, 9, 2, 10, 50, 0, 0, time.UTC), time.Date(2022, 9, 5, 9, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 10, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 0", time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 9, 4, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		schedule, err := Parse(c.spec)
+		if err != nil {
+			t.Fatalf("parse %v error: %v", c.spec, err)
+		}
+		next := schedule.Next(c.from)
+		if !next.Equal(c.next) {
+			t.Fatalf("spec %v from %v next %v, want %v", c.spec, c.from, next, c.next)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	var errorSpecs = []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	}
+	for _, spec := range errorSpecs {
+		if _, err := Parse(spec); err == nil {
+			t.Fatalf("spec %v should parse error", spec)
+		}
+	}
+}
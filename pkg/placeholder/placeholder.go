@@ -34,10 +34,13 @@ func (t Type) String() string {
 }
 
 const (
-	ContextType Type = "contexts"
-	InputType   Type = "inputs"
-	OutputType  Type = "outputs"
-	RandomType  Type = "randoms"
+	ContextType  Type = "contexts"
+	InputType    Type = "inputs"
+	OutputType   Type = "outputs"
+	RandomType   Type = "randoms"
+	MatrixType   Type = "matrix"
+	PipelineType Type = "pipeline"
+	TaskType     Type = "tasks"
 )
 
 type Handler func(placeholder string, values ...string) error
@@ -88,6 +91,39 @@ func MatchHolderFromHandler(needMatchString string, handlers map[Type]Handler) e
 			if err != nil {
 				return err
 			}
+		case MatrixType.String():
+			if handlers[MatrixType] == nil {
+				continue
+			}
+			if len(split) != 2 {
+				return fmt.Errorf("%v placeholder %v Format problem, use ${{ %v.xxx }}", MatrixType, placeholder, MatrixType)
+			}
+			err := handlers[MatrixType](placeholder, split[0], split[1])
+			if err != nil {
+				return err
+			}
+		case PipelineType.String():
+			if handlers[PipelineType] == nil {
+				continue
+			}
+			if len(split) != 2 {
+				return fmt.Errorf("%v placeholder %v Format problem, use ${{ %v.xxx }}", PipelineType, placeholder, PipelineType)
+			}
+			err := handlers[PipelineType](placeholder, split[0], split[1])
+			if err != nil {
+				return err
+			}
+		case TaskType.String():
+			if handlers[TaskType] == nil {
+				continue
+			}
+			if len(split) != 3 {
+				return fmt.Errorf("%v placeholder %v Format problem, use ${{ %v.alias.xxx }}", TaskType, placeholder, TaskType)
+			}
+			err := handlers[TaskType](placeholder, split[0], split[1], split[2])
+			if err != nil {
+				return err
+			}
 		case RandomType.String():
 			if handlers[RandomType] == nil {
 				continue
@@ -108,6 +144,9 @@ type ReplaceValue struct {
 	Inputs   apistructs.Inputs
 	Outputs  apistructs.Outputs
 	Contexts apistructs.Contexts
+	Matrix   map[string]string
+	Pipeline map[string]string
+	Tasks    map[string]string
 
 	PipelineId uint64
 	TaskId     uint64
@@ -183,6 +222,43 @@ func ReplacePlaceholder(needMatchString string, replaceValue *ReplaceValue, make
 			}
 			return nil
 		},
+		MatrixType: func(placeholder string, values ...string) error {
+			// ${{ matrix.xxx }}
+			if replaceValue.Matrix == nil {
+				return nil
+			}
+
+			value, ok := replaceValue.Matrix[values[1]]
+			if !ok {
+				return nil
+			}
+			needMatchString = strings.ReplaceAll(needMatchString, placeholder, value)
+			return nil
+		},
+		PipelineType: func(placeholder string, values ...string) error {
+			// ${{ pipeline.status }}
+			if replaceValue.Pipeline == nil {
+				return nil
+			}
+			value, ok := replaceValue.Pipeline[values[1]]
+			if !ok {
+				return nil
+			}
+			needMatchString = strings.ReplaceAll(needMatchString, placeholder, value)
+			return nil
+		},
+		TaskType: func(placeholder string, values ...string) error {
+			// ${{ tasks.alias.status }}
+			if replaceValue.Tasks == nil {
+				return nil
+			}
+			value, ok := replaceValue.Tasks[MakeOutputKey(values[1], values[2])]
+			if !ok {
+				return nil
+			}
+			needMatchString = strings.ReplaceAll(needMatchString, placeholder, value)
+			return nil
+		},
 		RandomType: func(placeholder string, values ...string) error {
 			// todo
 			return nil
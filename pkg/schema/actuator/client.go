@@ -15,7 +15,9 @@ package actuator
 
 import (
 	"eventops/apistructs"
+	"eventops/pkg/schema/pipeline"
 	"fmt"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 type Client struct {
@@ -90,6 +92,15 @@ func (a Client) Mutating() error {
 		}
 	}
 
+	if a.Kubernetes != nil {
+		if a.Kubernetes.TtlSecondsAfterFinished == 0 {
+			a.Kubernetes.TtlSecondsAfterFinished = 86400
+		}
+		if a.Kubernetes.Workspace != nil && a.Kubernetes.Workspace.AccessMode == "" {
+			a.Kubernetes.Workspace.AccessMode = "ReadWriteMany"
+		}
+	}
+
 	if a.Docker != nil {
 		if a.Docker.Ssh != nil {
 			if a.Docker.Ssh.Port == "" {
@@ -121,6 +132,91 @@ func (k *Kubernetes) Check() error {
 	if k.Config == "" {
 		return fmt.Errorf("kubernetes type actuator config can not empty")
 	}
+
+	switch k.NamespaceStrategy {
+	case "", PipelineNamespaceStrategy:
+		if k.Namespace != "" {
+			return fmt.Errorf("kubernetes type actuator namespace can only be used with namespaceStrategy %v", FixedNamespaceStrategy)
+		}
+	case FixedNamespaceStrategy:
+		if k.Namespace == "" {
+			return fmt.Errorf("kubernetes type actuator namespace can not empty when namespaceStrategy is %v", FixedNamespaceStrategy)
+		}
+	default:
+		return fmt.Errorf("kubernetes type actuator namespaceStrategy %v not support, only support %v", k.NamespaceStrategy, []string{PipelineNamespaceStrategy, FixedNamespaceStrategy})
+	}
+
+	if k.TtlSecondsAfterFinished < 0 {
+		return fmt.Errorf("kubernetes type actuator ttlSecondsAfterFinished can not less than 0")
+	}
+	for _, toleration := range k.Tolerations {
+		if err := toleration.check(); err != nil {
+			return err
+		}
+	}
+	for _, secret := range k.ImagePullSecrets {
+		if secret == "" {
+			return fmt.Errorf("kubernetes type actuator imagePullSecrets can not contain empty name")
+		}
+	}
+	if err := k.Workspace.check(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (k *Kubernetes) GetNamespaceStrategy() string {
+	if k.NamespaceStrategy == "" {
+		return PipelineNamespaceStrategy
+	}
+	return k.NamespaceStrategy
+}
+
+func (t Toleration) check() error {
+	switch t.Operator {
+	case "", "Equal":
+		if t.Key == "" && t.Value != "" {
+			return fmt.Errorf("kubernetes type actuator toleration key can not empty when value is set")
+		}
+	case "Exists":
+		if t.Value != "" {
+			return fmt.Errorf("kubernetes type actuator toleration value should be empty when operator is Exists")
+		}
+	default:
+		return fmt.Errorf("kubernetes type actuator toleration operator %v not support, only support [Equal, Exists]", t.Operator)
+	}
+
+	switch t.Effect {
+	case "", "NoSchedule", "PreferNoSchedule", "NoExecute":
+	default:
+		return fmt.Errorf("kubernetes type actuator toleration effect %v not support, only support [NoSchedule, PreferNoSchedule, NoExecute]", t.Effect)
+	}
+	if t.TolerationSeconds != nil && t.Effect != "NoExecute" {
+		return fmt.Errorf("kubernetes type actuator toleration tolerationSeconds can only be used with effect NoExecute")
+	}
+	return nil
+}
+
+func (w *Workspace) check() error {
+	if w == nil {
+		return nil
+	}
+	if w.Size == "" {
+		return fmt.Errorf("kubernetes type actuator workspace size can not empty")
+	}
+	quantity, err := resource.ParseQuantity(w.Size)
+	if err != nil {
+		return fmt.Errorf("kubernetes type actuator workspace size %v is invalid", w.Size)
+	}
+	if quantity.Sign() <= 0 {
+		return fmt.Errorf("kubernetes type actuator workspace size %v must be greater than 0", w.Size)
+	}
+
+	switch w.AccessMode {
+	case "", "ReadWriteOnce", "ReadWriteMany", "ReadWriteOncePod":
+	default:
+		return fmt.Errorf("kubernetes type actuator workspace accessMode %v not support, only support [ReadWriteOnce, ReadWriteMany, ReadWriteOncePod]", w.AccessMode)
+	}
 	return nil
 }
 
@@ -132,12 +228,18 @@ func (o *Os) Check() error {
 	if o.User == "" {
 		return fmt.Errorf("os type actuator user can not empty")
 	}
-	if o.Password == "" {
-		return fmt.Errorf("os type actuator password can not empty")
+	if o.Password == "" && o.PrivateKey == "" {
+		return fmt.Errorf("os type actuator password and privateKey can not both empty")
 	}
 	if o.Ip == "" {
 		return fmt.Errorf("os type actuator ip can not empty")
 	}
+	if err := o.checkPrivateKey(); err != nil {
+		return err
+	}
+	if err := o.checkHostKey(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -156,11 +258,73 @@ func (d *Docker) Check() error {
 		return err
 	}
 
+	if err := d.Docker.Check(); err != nil {
+		return fmt.Errorf("docker type actuator %v", err)
+	}
+	for _, auth := range d.RegistryAuths {
+		if auth.Registry == "" {
+			return fmt.Errorf("docker type actuator registryAuths registry can not empty")
+		}
+		if auth.Username == "" {
+			return fmt.Errorf("docker type actuator registryAuths %v username can not empty", auth.Registry)
+		}
+	}
+
 	return nil
 }
 
+const (
+	// PipelineNamespaceStrategy 每条流水线创建单独的 pipelines-<id> 命名空间, 回收时删除命名空间
+	PipelineNamespaceStrategy = "pipeline"
+	// FixedNamespaceStrategy 所有任务都在声明的命名空间中运行, 不会创建和删除命名空间
+	FixedNamespaceStrategy = "fixed"
+)
+
 type Kubernetes struct {
 	Config string `yaml:"config"`
+
+	NamespaceStrategy string `yaml:"namespaceStrategy,omitempty"`
+	Namespace         string `yaml:"namespace,omitempty"`
+
+	NodeSelector       map[string]string `yaml:"nodeSelector,omitempty"`
+	Tolerations        []Toleration      `yaml:"tolerations,omitempty"`
+	ServiceAccountName string            `yaml:"serviceAccountName,omitempty"`
+	ImagePullSecrets   []string          `yaml:"imagePullSecrets,omitempty"`
+	Labels             map[string]string `yaml:"labels,omitempty"`
+	Annotations        map[string]string `yaml:"annotations,omitempty"`
+	SecurityContext    *SecurityContext  `yaml:"securityContext,omitempty"`
+
+	// TtlSecondsAfterFinished job 结束多少秒后由 k8s 自动删除, 默认为 86400
+	TtlSecondsAfterFinished int32 `yaml:"ttlSecondsAfterFinished,omitempty"`
+
+	Workspace *Workspace `yaml:"workspace,omitempty"`
+}
+
+// WorkspaceMountPath 工作空间在任务容器中的挂载路径
+const WorkspaceMountPath = "/workspace"
+
+// Workspace 流水线的共享工作空间, 流水线开始时创建 pvc, 挂载到流水线的每个任务 pod 中, 回收流水线时删除
+type Workspace struct {
+	StorageClassName string `yaml:"storageClassName,omitempty"`
+	Size             string `yaml:"size"`
+	// AccessMode 默认为 ReadWriteMany, 并行的任务可能调度到不同的节点
+	AccessMode string `yaml:"accessMode,omitempty"`
+}
+
+type Toleration struct {
+	Key               string `yaml:"key,omitempty"`
+	Operator          string `yaml:"operator,omitempty"`
+	Value             string `yaml:"value,omitempty"`
+	Effect            string `yaml:"effect,omitempty"`
+	TolerationSeconds *int64 `yaml:"tolerationSeconds,omitempty"`
+}
+
+// SecurityContext 任务 pod 默认的安全上下文
+type SecurityContext struct {
+	RunAsUser    *int64 `yaml:"runAsUser,omitempty"`
+	RunAsGroup   *int64 `yaml:"runAsGroup,omitempty"`
+	RunAsNonRoot *bool  `yaml:"runAsNonRoot,omitempty"`
+	FsGroup      *int64 `yaml:"fsGroup,omitempty"`
 }
 
 type Os struct {
@@ -168,12 +332,33 @@ type Os struct {
 	Ip       string `json:"ip"`
 	Port     string `json:"port"`
 	Password string `yaml:"password,omitempty"`
+
+	// PrivateKey 私钥的内容, 和 password 至少声明一个
+	PrivateKey string `yaml:"privateKey,omitempty"`
+	Passphrase string `yaml:"passphrase,omitempty"`
+
+	// KnownHosts known_hosts 文件格式的内容, HostKeyFingerprint 为 SHA256:xxx 格式的主机公钥指纹, 至少声明一个用于校验主机公钥
+	KnownHosts         string `yaml:"knownHosts,omitempty"`
+	HostKeyFingerprint string `yaml:"hostKeyFingerprint,omitempty"`
+	// InsecureIgnoreHostKey 显式声明不校验主机公钥, 不能和 knownHosts hostKeyFingerprint 同时使用
+	InsecureIgnoreHostKey bool `yaml:"insecureIgnoreHostKey,omitempty"`
 }
 
 type Docker struct {
 	Ip   string `json:"ip"`
 	Port string `yaml:"port"`
 	Ssh  *Os    `yaml:"ssh,omitempty"`
+
+	// Docker 执行器上所有容器默认的配置, 任务中的 docker 配置会和它合并
+	pipeline.Docker `yaml:",inline"`
+	RegistryAuths   []RegistryAuth `yaml:"registryAuths,omitempty"`
+}
+
+// RegistryAuth 私有镜像仓库的认证信息, 根据镜像地址的仓库域名匹配
+type RegistryAuth struct {
+	Registry string `yaml:"registry"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
 }
 
 type Tunnel struct {
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package actuator
+
+import (
+	"errors"
+	"fmt"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+)
+
+func (o *Os) signer() (ssh.Signer, error) {
+	if o.Passphrase != "" {
+		return ssh.ParsePrivateKeyWithPassphrase([]byte(o.PrivateKey), []byte(o.Passphrase))
+	}
+	return ssh.ParsePrivateKey([]byte(o.PrivateKey))
+}
+
+func (o *Os) checkPrivateKey() error {
+	if o.PrivateKey == "" {
+		if o.Passphrase != "" {
+			return fmt.Errorf("os type actuator passphrase can only be used with privateKey")
+		}
+		return nil
+	}
+
+	if _, err := o.signer(); err != nil {
+		var missingErr *ssh.PassphraseMissingError
+		if errors.As(err, &missingErr) {
+			return fmt.Errorf("os type actuator privateKey is encrypted, passphrase can not empty")
+		}
+		return fmt.Errorf("os type actuator privateKey parse error: %v", err)
+	}
+	return nil
+}
+
+func (o *Os) checkHostKey() error {
+	if o.InsecureIgnoreHostKey {
+		if o.KnownHosts != "" || o.HostKeyFingerprint != "" {
+			return fmt.Errorf("os type actuator insecureIgnoreHostKey can not be used with knownHosts or hostKeyFingerprint")
+		}
+		return nil
+	}
+	if o.KnownHosts == "" && o.HostKeyFingerprint == "" {
+		return fmt.Errorf("os type actuator knownHosts and hostKeyFingerprint can not both empty, set insecureIgnoreHostKey to skip host key verification")
+	}
+
+	rest := []byte(o.KnownHosts)
+	for len(strings.TrimSpace(string(rest))) > 0 {
+		var err error
+		_, _, _, _, rest, err = ssh.ParseKnownHosts(rest)
+		if err != nil {
+			return fmt.Errorf("os type actuator knownHosts parse error: %v", err)
+		}
+	}
+
+	if o.HostKeyFingerprint != "" && !strings.HasPrefix(o.HostKeyFingerprint, "SHA256:") {
+		return fmt.Errorf("os type actuator hostKeyFingerprint should be SHA256 format, example: SHA256:xxx")
+	}
+	return nil
+}
+
+// ClientConfig 根据配置生成 ssh 连接的配置, 不会开启 agent forwarding
+func (o *Os) ClientConfig() (*ssh.ClientConfig, error) {
+	var auths []ssh.AuthMethod
+	if o.PrivateKey != "" {
+		signer, err := o.signer()
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+	if o.Password != "" {
+		auths = append(auths, ssh.Password(o.Password))
+	}
+
+	hostKeyCallback, err := o.hostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ssh.ClientConfig{
+		User:            o.User,
+		Auth:            auths,
+		HostKeyCallback: hostKeyCallback,
+	}, nil
+}
+
+// hostKeyCallback 只有显式声明 insecureIgnoreHostKey 时才不校验主机公钥
+func (o *Os) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	if o.InsecureIgnoreHostKey {
+		logrus.Warnf("ssh %v@%v insecureIgnoreHostKey is set, host key will not be verified", o.User, o.Ip)
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	var callbacks []ssh.HostKeyCallback
+
+	if o.KnownHosts != "" {
+		callback, err := knownHostsCallback(o.KnownHosts)
+		if err != nil {
+			return nil, err
+		}
+		callbacks = append(callbacks, callback)
+	}
+
+	if o.HostKeyFingerprint != "" {
+		callbacks = append(callbacks, func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			fingerprint := ssh.FingerprintSHA256(key)
+			if fingerprint != o.HostKeyFingerprint {
+				return fmt.Errorf("host %v key fingerprint %v not match hostKeyFingerprint %v, the host key may have changed", hostname, fingerprint, o.HostKeyFingerprint)
+			}
+			return nil
+		})
+	}
+
+	// 已经保存的执行器定义没有经过新的校验, 连接时也需要拒绝
+	if len(callbacks) == 0 {
+		return nil, fmt.Errorf("ssh %v@%v host key verification not configured, set knownHosts, hostKeyFingerprint or insecureIgnoreHostKey", o.User, o.Ip)
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		for _, callback := range callbacks {
+			if err := callback(hostname, remote, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+// knownHostsCallback knownhosts 只支持读取文件, 先把内容写到临时文件中
+func knownHostsCallback(content string) (ssh.HostKeyCallback, error) {
+	f, err := ioutil.TempFile("", "known_hosts")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if _, err := f.WriteString(content); err != nil {
+		return nil, err
+	}
+
+	callback, err := knownhosts.New(f.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) {
+			if len(keyErr.Want) == 0 {
+				return fmt.Errorf("host %v is unknown, key %v not found in knownHosts", hostname, ssh.FingerprintSHA256(key))
+			}
+			return fmt.Errorf("host %v key %v not match knownHosts, the host key may have changed", hostname, ssh.FingerprintSHA256(key))
+		}
+
+		var revokedErr *knownhosts.RevokedError
+		if errors.As(err, &revokedErr) {
+			return fmt.Errorf("host %v key %v is revoked in knownHosts", hostname, ssh.FingerprintSHA256(key))
+		}
+		return err
+	}, nil
+}
@@ -0,0 +1,270 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package event
+
+import (
+	"fmt"
+	"github.com/tidwall/gjson"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Operator string
+
+const (
+	EqualsOperator    Operator = "equals"
+	NotEqualsOperator Operator = "notEquals"
+	RegexOperator     Operator = "regex"
+	InOperator        Operator = "in"
+	ExistsOperator    Operator = "exists"
+	GtOperator        Operator = "gt"
+	GteOperator       Operator = "gte"
+	LtOperator        Operator = "lt"
+	LteOperator       Operator = "lte"
+	ContainsOperator  Operator = "contains"
+)
+
+var OperatorList = []Operator{EqualsOperator, NotEqualsOperator, RegexOperator, InOperator, ExistsOperator, GtOperator, GteOperator, LtOperator, LteOperator, ContainsOperator}
+
+// Filter 有两种形式
+// 1. expr + operator + value/matches 从事件的 json 中取值进行比较, 不声明 operator 的时候默认为 in
+// 2. all 或者 any 对多个 filter 进行分组, all 需要全部通过, any 只需要一个通过
+// not 对结果取反
+type Filter struct {
+	Expr     string   `yaml:"expr,omitempty"`
+	Operator Operator `yaml:"operator,omitempty"`
+	Value    string   `yaml:"value,omitempty"`
+	Matches  []string `yaml:"matches,omitempty"`
+	Not      bool     `yaml:"not,omitempty"`
+
+	All []Filter `yaml:"all,omitempty"`
+	Any []Filter `yaml:"any,omitempty"`
+}
+
+var regexCache sync.Map
+
+func getRegexp(expr string) (*regexp.Regexp, error) {
+	if value, ok := regexCache.Load(expr); ok {
+		return value.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.Store(expr, re)
+	return re, nil
+}
+
+func (filter Filter) getOperator() Operator {
+	if filter.Operator == "" {
+		return InOperator
+	}
+	return filter.Operator
+}
+
+func (filter Filter) isGroup() bool {
+	return len(filter.All) > 0 || len(filter.Any) > 0
+}
+
+func (filter Filter) check() error {
+	if filter.isGroup() {
+		if filter.Expr != "" || filter.Operator != "" || filter.Value != "" || len(filter.Matches) > 0 {
+			return fmt.Errorf("trigger definition filters field: all or any can not use with expr operator value matches")
+		}
+		if len(filter.All) > 0 && len(filter.Any) > 0 {
+			return fmt.Errorf("trigger definition filters field: all and any can not use at the same time")
+		}
+
+		for _, child := range append(filter.All, filter.Any...) {
+			if err := child.check(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if filter.Expr == "" {
+		return fmt.Errorf("trigger definition filters field: expr can not empty")
+	}
+
+	var findOperator = false
+	for _, operator := range OperatorList {
+		if operator == filter.getOperator() {
+			findOperator = true
+			break
+		}
+	}
+	if !findOperator {
+		return fmt.Errorf("trigger definition filters field: operator %v not support, use %v", filter.Operator, OperatorList)
+	}
+
+	switch filter.getOperator() {
+	case InOperator:
+		if len(filter.Matches) == 0 {
+			return fmt.Errorf("trigger definition filters field: expr %v matches can not empty", filter.Expr)
+		}
+	case ExistsOperator:
+	case GtOperator, GteOperator, LtOperator, LteOperator:
+		if _, err := strconv.ParseFloat(filter.Value, 64); err != nil {
+			return fmt.Errorf("trigger definition filters field: expr %v operator %v value %v not number", filter.Expr, filter.Operator, filter.Value)
+		}
+	case RegexOperator:
+		if _, err := getRegexp(filter.Value); err != nil {
+			return fmt.Errorf("trigger definition filters field: expr %v regex %v compile error %v", filter.Expr, filter.Value, err)
+		}
+	case EqualsOperator, NotEqualsOperator, ContainsOperator:
+		if filter.Value == "" {
+			return fmt.Errorf("trigger definition filters field: expr %v operator %v value can not empty", filter.Expr, filter.Operator)
+		}
+	}
+	return nil
+}
+
+func (filter Filter) String() string {
+	var str string
+	switch {
+	case len(filter.All) > 0:
+		var children []string
+		for _, child := range filter.All {
+			children = append(children, child.String())
+		}
+		str = fmt.Sprintf("all(%v)", strings.Join(children, ", "))
+	case len(filter.Any) > 0:
+		var children []string
+		for _, child := range filter.Any {
+			children = append(children, child.String())
+		}
+		str = fmt.Sprintf("any(%v)", strings.Join(children, ", "))
+	case filter.getOperator() == InOperator:
+		str = fmt.Sprintf("%v %v %v", filter.Expr, InOperator, filter.Matches)
+	case filter.getOperator() == ExistsOperator:
+		str = fmt.Sprintf("%v %v", filter.Expr, ExistsOperator)
+	default:
+		str = fmt.Sprintf("%v %v %v", filter.Expr, filter.Operator, filter.Value)
+	}
+
+	if filter.Not {
+		return fmt.Sprintf("not %v", str)
+	}
+	return str
+}
+
+// Match 判断事件内容是否通过 filter, 不通过的时候返回拒绝的 filter 描述
+func (filter Filter) Match(content string) (bool, string) {
+	pass, reason := filter.match(content)
+	if filter.Not {
+		if pass {
+			return false, filter.String()
+		}
+		return true, ""
+	}
+	return pass, reason
+}
+
+func (filter Filter) match(content string) (bool, string) {
+	if len(filter.All) > 0 {
+		for _, child := range filter.All {
+			if pass, reason := child.Match(content); !pass {
+				return false, reason
+			}
+		}
+		return true, ""
+	}
+
+	if len(filter.Any) > 0 {
+		for _, child := range filter.Any {
+			if pass, _ := child.Match(content); pass {
+				return true, ""
+			}
+		}
+		return false, filter.String()
+	}
+
+	result := gjson.Get(content, filter.Expr)
+	if filter.matchValue(result) {
+		return true, ""
+	}
+	return false, filter.String()
+}
+
+func (filter Filter) matchValue(result gjson.Result) bool {
+	switch filter.getOperator() {
+	case ExistsOperator:
+		return result.Exists()
+	case InOperator:
+		for _, match := range filter.Matches {
+			if match == result.String() {
+				return true
+			}
+		}
+		return false
+	case EqualsOperator:
+		return result.Exists() && result.String() == filter.Value
+	case NotEqualsOperator:
+		return result.String() != filter.Value
+	case RegexOperator:
+		re, err := getRegexp(filter.Value)
+		if err != nil || !result.Exists() {
+			return false
+		}
+		return re.MatchString(result.String())
+	case ContainsOperator:
+		if result.IsArray() {
+			for _, item := range result.Array() {
+				if item.String() == filter.Value {
+					return true
+				}
+			}
+			return false
+		}
+		return result.Exists() && strings.Contains(result.String(), filter.Value)
+	case GtOperator, GteOperator, LtOperator, LteOperator:
+		if !result.Exists() {
+			return false
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(result.String()), 64)
+		if err != nil {
+			return false
+		}
+		compareValue, err := strconv.ParseFloat(filter.Value, 64)
+		if err != nil {
+			return false
+		}
+
+		switch filter.getOperator() {
+		case GtOperator:
+			return value > compareValue
+		case GteOperator:
+			return value >= compareValue
+		case LtOperator:
+			return value < compareValue
+		default:
+			return value <= compareValue
+		}
+	}
+	return false
+}
+
+// MatchFilters 所有的 filter 都通过才算通过
+func MatchFilters(filters []Filter, content string) (bool, string) {
+	for _, filter := range filters {
+		if pass, reason := filter.Match(content); !pass {
+			return false, reason
+		}
+	}
+	return true, ""
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package event
+
+import (
+	"testing"
+)
+
+func TestMatchFilters(t *testing.T) {
+	var content = `{"values": {"name": "kakj", "ref": "refs/heads/release-1.0", "count": 12, "labels": ["bug", "ci"]}}`
+
+	var cases = []struct {
+		filter Filter
+		pass   bool
+	}{
+		{Filter{Expr: "values.name", Matches: []string{"kakj", "kakj-go"}}, true},
+		{Filter{Expr: "values.name", Matches: []string{"kakj-go"}}, false},
+		{Filter{Expr: "values.name", Operator: EqualsOperator, Value: "kakj"}, true},
+		{Filter{Expr: "values.name", Operator: NotEqualsOperator, Value: "kakj"}, false},
+		{Filter{Expr: "values.ref", Operator: RegexOperator, Value: "^refs/heads/release-.*$"}, true},
+		{Filter{Expr: "values.count", Operator: GtOperator, Value: "10"}, true},
+		{Filter{Expr: "values.count", Operator: LteOperator, Value: "10"}, false},
+		{Filter{Expr: "values.missing", Operator: ExistsOperator}, false},
+		{Filter{Expr: "values.missing", Operator: ExistsOperator, Not: true}, true},
+		{Filter{Expr: "values.labels", Operator: ContainsOperator, Value: "ci"}, true},
+		{Filter{Any: []Filter{
+			{Expr: "values.name", Operator: EqualsOperator, Value: "other"},
+			{Expr: "values.count", Operator: GteOperator, Value: "12"},
+		}}, true},
+		{Filter{All: []Filter{
+			{Expr: "values.name", Operator: EqualsOperator, Value: "kakj"},
+			{Expr: "values.count", Operator: LtOperator, Value: "12"},
+		}}, false},
+	}
+	for _, c := range cases {
+		if err := c.filter.check(); err != nil {
+			t.Fatalf("filter %v check error: %v", c.filter, err)
+		}
+		pass, reason := MatchFilters([]Filter{c.filter}, content)
+		if pass != c.pass {
+			t.Fatalf("filter %v pass %v, want %v", c.filter, pass, c.pass)
+		}
+		if !pass && reason == "" {
+			t.Fatalf("filter %v not pass reason can not empty", c.filter)
+		}
+	}
+}
+
+func TestFilterCheck(t *testing.T) {
+	var errorFilters = []Filter{
+		{Expr: "values.name"},
+		{Expr: "values.name", Operator: "unknown", Value: "kakj"},
+		{Expr: "values.name", Operator: RegexOperator, Value: "("},
+		{Expr: "values.count", Operator: GtOperator, Value: "abc"},
+		{Expr: "values.name", All: []Filter{{Expr: "values.name", Matches: []string{"kakj"}}}},
+		{Any: []Filter{{Expr: "values.name"}}},
+	}
+	for _, filter := range errorFilters {
+		if err := filter.check(); err == nil {
+			t.Fatalf("filter %v should check error", filter)
+		}
+	}
+}
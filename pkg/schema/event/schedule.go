@@ -0,0 +1,81 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package event
+
+import (
+	"eventops/apistructs"
+	"eventops/pkg/cron"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 定时触发器生成的事件使用保留的事件名称, 每个触发器只会被自己的定时事件触发
+const (
+	ScheduleEventNamePrefix = apistructs.ReservedEventNamePrefix + "schedule."
+	ScheduleEventVersion    = "1.0"
+)
+
+func ScheduleEventName(triggerName string) string {
+	return ScheduleEventNamePrefix + triggerName
+}
+
+func IsScheduleEventName(eventName string) bool {
+	return strings.HasPrefix(eventName, ScheduleEventNamePrefix)
+}
+
+type CatchUp string
+
+const (
+	// SkipCatchUp 错过的触发全部跳过
+	SkipCatchUp CatchUp = "skip"
+	// OnceCatchUp 错过的触发只补一次
+	OnceCatchUp CatchUp = "once"
+)
+
+type Schedule struct {
+	Cron     string  `yaml:"cron,omitempty"`
+	Timezone string  `yaml:"timezone,omitempty"`
+	CatchUp  CatchUp `yaml:"catchUp,omitempty"`
+}
+
+func (s Schedule) GetCatchUp() CatchUp {
+	if s.CatchUp == "" {
+		return SkipCatchUp
+	}
+	return s.CatchUp
+}
+
+func (s Schedule) GetLocation() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(s.Timezone)
+}
+
+func (s Schedule) check() error {
+	if s.Cron == "" {
+		return fmt.Errorf("trigger definition schedule field: cron can not empty")
+	}
+	if _, err := cron.Parse(s.Cron); err != nil {
+		return fmt.Errorf("trigger definition schedule field: %v", err)
+	}
+	if _, err := s.GetLocation(); err != nil {
+		return fmt.Errorf("trigger definition schedule field: timezone %v error: %v", s.Timezone, err)
+	}
+	if s.GetCatchUp() != SkipCatchUp && s.GetCatchUp() != OnceCatchUp {
+		return fmt.Errorf("trigger definition schedule field: catchUp only support %v %v", SkipCatchUp, OnceCatchUp)
+	}
+	return nil
+}
@@ -23,6 +23,12 @@ type TriggerPipeline struct {
 	Image   string        `yaml:"image,omitempty"`
 	Inputs  []InputsValue `yaml:"inputs,omitempty"`
 	Filters []Filter      `yaml:"filters,omitempty"`
+	// Timeout 覆盖流水线定义中的超时时间(秒), 为 0 时不覆盖
+	Timeout int64 `yaml:"timeout,omitempty"`
+	// Concurrency 覆盖流水线定义中的并发组
+	Concurrency *pipeline.Concurrency `yaml:"concurrency,omitempty"`
+	// Priority 排队时优先级高的流水线先执行, 事件的 priority 标签会覆盖这里的值
+	Priority int `yaml:"priority,omitempty"`
 }
 
 type Trigger struct {
@@ -32,6 +38,8 @@ type Trigger struct {
 	EventVersion string            `yaml:"eventVersion,omitempty"`
 	Pipelines    []TriggerPipeline `yaml:"pipelines,omitempty"`
 	Filters      []Filter          `yaml:"filters,omitempty"`
+	// 声明了 schedule 的触发器会按照 cron 定时生成事件
+	Schedule *Schedule `yaml:"schedule,omitempty"`
 }
 
 type InputsValue struct {
@@ -49,22 +57,19 @@ func (input InputsValue) check() error {
 	return nil
 }
 
-type Filter struct {
-	Expr    string   `yaml:"expr,omitempty"`
-	Matches []string `yaml:"matches,omitempty"`
-}
-
-func (filter Filter) check() error {
-	if filter.Expr == "" {
-		return fmt.Errorf("trigger definition filters field: expr can not empty")
-	}
-	if len(filter.Matches) == 0 {
-		return fmt.Errorf("trigger definition filters field: matches can not empty")
+func (t *Trigger) Mutating(creater string) error {
+	if t.Schedule != nil {
+		if t.EventName == "" {
+			t.EventName = ScheduleEventName(t.Name)
+		}
+		if t.EventVersion == "" {
+			t.EventVersion = ScheduleEventVersion
+		}
+		if t.EventCreater == "" {
+			t.EventCreater = creater
+		}
 	}
-	return nil
-}
 
-func (t *Trigger) Mutating(creater string) error {
 	for index, pipe := range t.Pipelines {
 
 		imageCreater := pipeline.GetImageCreater(pipe.Image)
@@ -104,6 +109,14 @@ func (t *Trigger) Check(creater string) error {
 		if pipeline.GetImageCreater(pipe.Image) != creater {
 			return fmt.Errorf("trigger definition pipeline field: image user should use youself")
 		}
+		if pipe.Timeout < 0 {
+			return fmt.Errorf("trigger definition pipeline field: timeout can not less than 0")
+		}
+		if pipe.Concurrency != nil {
+			if err := pipe.Concurrency.Check(); err != nil {
+				return fmt.Errorf("trigger definition pipeline field: %v", err)
+			}
+		}
 
 		for _, input := range pipe.Inputs {
 			if err := input.check(); err != nil {
@@ -124,5 +137,20 @@ func (t *Trigger) Check(creater string) error {
 		}
 	}
 
+	if t.Schedule != nil {
+		if err := t.Schedule.check(); err != nil {
+			return err
+		}
+		// 定时生成的事件创建人是触发器的创建人
+		if t.EventCreater != creater {
+			return fmt.Errorf("trigger definition field: eventCreater should use youself when schedule is set")
+		}
+		if t.EventName != ScheduleEventName(t.Name) || t.EventVersion != ScheduleEventVersion {
+			return fmt.Errorf("trigger definition field: eventName and eventVersion should be empty when schedule is set, schedule trigger only listen %v %v", ScheduleEventName(t.Name), ScheduleEventVersion)
+		}
+	} else if IsScheduleEventName(t.EventName) {
+		return fmt.Errorf("trigger definition field: eventName %v is reserved for schedule trigger", t.EventName)
+	}
+
 	return nil
 }
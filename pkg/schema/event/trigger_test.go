@@ -0,0 +1,48 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package event
+
+import (
+	"testing"
+)
+
+func TestScheduleTriggerEventName(t *testing.T) {
+	var trigger = Trigger{
+		Name:      "nightly",
+		Schedule:  &Schedule{Cron: "0 2 * * *"},
+		Pipelines: []TriggerPipeline{{Image: "build"}},
+	}
+	if err := trigger.Mutating("kakj"); err != nil {
+		t.Fatalf("mutating error: %v", err)
+	}
+	if trigger.EventName != ScheduleEventName("nightly") || trigger.EventVersion != ScheduleEventVersion || trigger.EventCreater != "kakj" {
+		t.Fatalf("schedule trigger event %v %v %v not match", trigger.EventName, trigger.EventVersion, trigger.EventCreater)
+	}
+	if err := trigger.Check("kakj"); err != nil {
+		t.Fatalf("check error: %v", err)
+	}
+
+	// 定时触发器不能监听其他事件
+	trigger.EventName = "push"
+	if err := trigger.Check("kakj"); err == nil {
+		t.Fatalf("schedule trigger listen other event should error")
+	}
+
+	// 其他触发器不能监听定时事件
+	trigger.Schedule = nil
+	trigger.EventName = ScheduleEventName("nightly")
+	if err := trigger.Check("kakj"); err == nil {
+		t.Fatalf("trigger listen schedule event should error")
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package event
+
+import (
+	"fmt"
+)
+
+type WebhookProvider string
+
+const (
+	GithubWebhookProvider WebhookProvider = "github"
+	GitlabWebhookProvider WebhookProvider = "gitlab"
+	GiteaWebhookProvider  WebhookProvider = "gitea"
+)
+
+var WebhookProviderList = []WebhookProvider{GithubWebhookProvider, GitlabWebhookProvider, GiteaWebhookProvider}
+
+const DefaultWebhookEventVersion = "1.0"
+
+type Webhook struct {
+	Name     string          `yaml:"name,omitempty"`
+	Provider WebhookProvider `yaml:"provider,omitempty"`
+	// github gitea 用来校验签名, gitlab 和 X-Gitlab-Token 进行比较
+	Secret       string `yaml:"secret,omitempty"`
+	EventVersion string `yaml:"eventVersion,omitempty"`
+	// 只接收这些类型的事件, 为空的时候全部接收
+	Events []string `yaml:"events,omitempty"`
+	Users  []string `yaml:"users,omitempty"`
+}
+
+func (w *Webhook) Mutating() error {
+	if w.EventVersion == "" {
+		w.EventVersion = DefaultWebhookEventVersion
+	}
+	return nil
+}
+
+func (w *Webhook) Check() error {
+	if w.Name == "" {
+		return fmt.Errorf("webhook field: name can not empty")
+	}
+	if w.Secret == "" {
+		return fmt.Errorf("webhook field: secret can not empty")
+	}
+	if w.EventVersion == "" {
+		return fmt.Errorf("webhook field: eventVersion can not empty")
+	}
+
+	var findProvider = false
+	for _, provider := range WebhookProviderList {
+		if provider == w.Provider {
+			findProvider = true
+			break
+		}
+	}
+	if !findProvider {
+		return fmt.Errorf("webhook field: provider %v not support, use %v", w.Provider, WebhookProviderList)
+	}
+	return nil
+}
+
+// AcceptEvent 判断事件类型是否在 events 中
+func (w *Webhook) AcceptEvent(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, event := range w.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildEventName webhook 生成的事件名称为 provider.eventType, 例如 github.push
+func (w *Webhook) BuildEventName(eventType string) string {
+	return fmt.Sprintf("%v.%v", w.Provider, eventType)
+}
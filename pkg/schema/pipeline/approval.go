@@ -0,0 +1,34 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Approval struct {
+	// Approvers 为空时只有流水线的创建者可以审批
+	Approvers []string `yaml:"approvers,omitempty"`
+	Message   string   `yaml:"message,omitempty"`
+}
+
+func (a Approval) check() error {
+	for _, approver := range a.Approvers {
+		if strings.TrimSpace(approver) == "" {
+			return fmt.Errorf("approval approver can not empty")
+		}
+	}
+	return nil
+}
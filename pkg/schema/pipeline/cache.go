@@ -0,0 +1,50 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pipeline
+
+import (
+	"eventops/pkg/placeholder"
+	"fmt"
+	"strings"
+)
+
+type Cache struct {
+	Key       string   `yaml:"key,omitempty"`
+	HashFiles []string `yaml:"hashFiles,omitempty"`
+	Paths     []string `yaml:"paths,omitempty"`
+}
+
+func (c Cache) check() error {
+	if strings.TrimSpace(c.Key) == "" {
+		return fmt.Errorf("cache key can not empty")
+	}
+
+	if len(c.Paths) == 0 {
+		return fmt.Errorf("cache key %v paths can not empty", c.Key)
+	}
+
+	for _, path := range c.Paths {
+		// 缓存的目录需要是绝对路径, 保存和恢复的时候都是基于 / 目录
+		if !strings.HasPrefix(path, "/") && !strings.HasPrefix(path, placeholder.Left) {
+			return fmt.Errorf("cache key %v path %v should be absolute path", c.Key, path)
+		}
+	}
+
+	for _, file := range c.HashFiles {
+		if strings.TrimSpace(file) == "" {
+			return fmt.Errorf("cache key %v hashFiles can not have empty value", c.Key)
+		}
+	}
+	return nil
+}
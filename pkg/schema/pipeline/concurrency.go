@@ -0,0 +1,77 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pipeline
+
+import (
+	"eventops/apistructs"
+	"eventops/pkg/placeholder"
+	"fmt"
+	"strings"
+)
+
+type ConcurrencyPolicy string
+
+const (
+	// QueueConcurrencyPolicy 排队等待同组的流水线结束后按照创建顺序执行
+	QueueConcurrencyPolicy ConcurrencyPolicy = "queue"
+	// CancelPreviousConcurrencyPolicy 取消同组中运行和排队的流水线
+	CancelPreviousConcurrencyPolicy ConcurrencyPolicy = "cancel-previous"
+	// SkipConcurrencyPolicy 同组中有流水线时不执行新的流水线
+	SkipConcurrencyPolicy ConcurrencyPolicy = "skip"
+)
+
+var ConcurrencyPolicyList = []ConcurrencyPolicy{QueueConcurrencyPolicy, CancelPreviousConcurrencyPolicy, SkipConcurrencyPolicy}
+
+type Concurrency struct {
+	// Group 同一个组同时只会运行一条流水线, 可以使用 ${{ inputs.xxx }} 占位符
+	Group  string            `yaml:"group,omitempty"`
+	Policy ConcurrencyPolicy `yaml:"policy,omitempty"`
+}
+
+func (c Concurrency) GetPolicy() ConcurrencyPolicy {
+	if c.Policy == "" {
+		return QueueConcurrencyPolicy
+	}
+	return c.Policy
+}
+
+func (c Concurrency) Check() error {
+	if strings.TrimSpace(c.Group) == "" {
+		return fmt.Errorf("concurrency group can not empty")
+	}
+
+	var findPolicy = false
+	for _, policy := range ConcurrencyPolicyList {
+		if c.GetPolicy() == policy {
+			findPolicy = true
+			break
+		}
+	}
+	if !findPolicy {
+		return fmt.Errorf("concurrency policy only support %v", ConcurrencyPolicyList)
+	}
+
+	// 组名在流水线创建之前确定, 只能使用流水线的入参
+	for _, holder := range placeholder.PhRe.FindAllString(c.Group, -1) {
+		if !strings.HasPrefix(holder, placeholder.Left+placeholder.InputType.String()+".") {
+			return fmt.Errorf("concurrency group only support ${{ %v.xxx }} placeholder, can not use %v", placeholder.InputType, holder)
+		}
+	}
+	return nil
+}
+
+// RenderGroup 使用流水线的入参替换组名中的占位符
+func (c Concurrency) RenderGroup(inputs apistructs.Inputs) string {
+	return placeholder.ReplacePlaceholder(c.Group, &placeholder.ReplaceValue{Inputs: inputs}, false)
+}
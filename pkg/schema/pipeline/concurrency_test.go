@@ -0,0 +1,44 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pipeline
+
+import (
+	"eventops/apistructs"
+	"testing"
+)
+
+func TestConcurrency(t *testing.T) {
+	concurrency := Concurrency{Group: "deploy-${{ inputs.env }}"}
+	if err := concurrency.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if concurrency.GetPolicy() != QueueConcurrencyPolicy {
+		t.Fatalf("default policy %v, want %v", concurrency.GetPolicy(), QueueConcurrencyPolicy)
+	}
+
+	group := concurrency.RenderGroup(apistructs.Inputs{"env": {Name: "env", Value: "prod", Type: apistructs.EnvType}})
+	if group != "deploy-prod" {
+		t.Fatalf("group %v, want deploy-prod", group)
+	}
+
+	for _, invalid := range []Concurrency{
+		{Group: ""},
+		{Group: "deploy", Policy: "wait"},
+		{Group: "deploy-${{ contexts.env }}"},
+	} {
+		if err := invalid.Check(); err == nil {
+			t.Fatalf("concurrency %v check should error", invalid)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	AlwaysPullPolicy       = "always"
+	IfNotPresentPullPolicy = "ifNotPresent"
+	NeverPullPolicy        = "never"
+)
+
+var PullPolicyList = []string{AlwaysPullPolicy, IfNotPresentPullPolicy, NeverPullPolicy}
+
+// Docker [docker] 类型任务的容器配置, 执行器定义中也可以声明, 两者会合并
+type Docker struct {
+	// Volumes 格式为 source:target[:ro|rw], source 可以是宿主机的绝对路径或者 volume 名称
+	Volumes     []string          `yaml:"volumes,omitempty"`
+	Env         map[string]string `yaml:"env,omitempty"`
+	NetworkMode string            `yaml:"networkMode,omitempty"`
+	Privileged  bool              `yaml:"privileged,omitempty"`
+	User        string            `yaml:"user,omitempty"`
+	// PullPolicy 默认为 always
+	PullPolicy string `yaml:"pullPolicy,omitempty"`
+}
+
+func (d *Docker) Check() error {
+	if d == nil {
+		return nil
+	}
+
+	for _, volume := range d.Volumes {
+		split := strings.Split(volume, ":")
+		if len(split) != 2 && len(split) != 3 {
+			return fmt.Errorf("docker volume %v format error, use source:target[:ro|rw]", volume)
+		}
+		if split[0] == "" {
+			return fmt.Errorf("docker volume %v source can not empty", volume)
+		}
+		if !strings.HasPrefix(split[1], "/") {
+			return fmt.Errorf("docker volume %v target should be absolute path", volume)
+		}
+		if len(split) == 3 && split[2] != "ro" && split[2] != "rw" {
+			return fmt.Errorf("docker volume %v mode only support [ro, rw]", volume)
+		}
+	}
+
+	for key := range d.Env {
+		if key == "" || strings.Contains(key, "=") {
+			return fmt.Errorf("docker env name %v is invalid", key)
+		}
+	}
+
+	switch d.PullPolicy {
+	case "", AlwaysPullPolicy, IfNotPresentPullPolicy, NeverPullPolicy:
+	default:
+		return fmt.Errorf("docker pullPolicy %v not support, only support %v", d.PullPolicy, PullPolicyList)
+	}
+	return nil
+}
+
+// Merge 合并执行器和任务的配置, volumes 追加, env 和其他配置以任务为准, privileged 任意一方开启即开启
+func (d *Docker) Merge(task *Docker) *Docker {
+	var result = &Docker{
+		Env: map[string]string{},
+	}
+	for _, item := range []*Docker{d, task} {
+		if item == nil {
+			continue
+		}
+
+		result.Volumes = append(result.Volumes, item.Volumes...)
+		for key, value := range item.Env {
+			result.Env[key] = value
+		}
+		if item.NetworkMode != "" {
+			result.NetworkMode = item.NetworkMode
+		}
+		if item.User != "" {
+			result.User = item.User
+		}
+		if item.PullPolicy != "" {
+			result.PullPolicy = item.PullPolicy
+		}
+		result.Privileged = result.Privileged || item.Privileged
+	}
+
+	if result.PullPolicy == "" {
+		result.PullPolicy = AlwaysPullPolicy
+	}
+	return result
+}
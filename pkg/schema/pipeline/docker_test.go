@@ -0,0 +1,73 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pipeline
+
+import (
+	"testing"
+)
+
+func TestDockerCheck(t *testing.T) {
+	valid := &Docker{
+		Volumes:    []string{"/var/run/docker.sock:/var/run/docker.sock", "cache:/cache:ro"},
+		Env:        map[string]string{"GOPROXY": "https://goproxy.cn"},
+		PullPolicy: IfNotPresentPullPolicy,
+	}
+	if err := valid.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, invalid := range []*Docker{
+		{Volumes: []string{"/data"}},
+		{Volumes: []string{":/data"}},
+		{Volumes: []string{"/data:data"}},
+		{Volumes: []string{"/data:/data:rx"}},
+		{Env: map[string]string{"A=B": "C"}},
+		{PullPolicy: "sometimes"},
+	} {
+		if err := invalid.Check(); err == nil {
+			t.Fatalf("docker %v check should error", invalid)
+		}
+	}
+}
+
+func TestDockerMerge(t *testing.T) {
+	var actuatorDocker = &Docker{
+		Volumes:     []string{"/var/run/docker.sock:/var/run/docker.sock"},
+		Env:         map[string]string{"A": "actuator", "B": "actuator"},
+		NetworkMode: "host",
+		Privileged:  true,
+	}
+	var taskDocker = &Docker{
+		Volumes:    []string{"cache:/cache"},
+		Env:        map[string]string{"B": "task"},
+		User:       "1000",
+		PullPolicy: NeverPullPolicy,
+	}
+
+	result := actuatorDocker.Merge(taskDocker)
+	if len(result.Volumes) != 2 || result.Volumes[1] != "cache:/cache" {
+		t.Fatalf("merge volumes %v error", result.Volumes)
+	}
+	if result.Env["A"] != "actuator" || result.Env["B"] != "task" {
+		t.Fatalf("merge env %v error", result.Env)
+	}
+	if result.NetworkMode != "host" || !result.Privileged || result.User != "1000" || result.PullPolicy != NeverPullPolicy {
+		t.Fatalf("merge result %v error", result)
+	}
+
+	var empty *Docker
+	if empty.Merge(nil).PullPolicy != AlwaysPullPolicy {
+		t.Fatalf("default pullPolicy should be %v", AlwaysPullPolicy)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pipeline
+
+import (
+	"eventops/apistructs"
+	"eventops/pkg/placeholder"
+	"fmt"
+	"gopkg.in/yaml.v3"
+)
+
+// PipelineStatusKey ${{ pipeline.status }} 为 dag 结束时流水线的状态, 只能在 finally 任务中使用
+const PipelineStatusKey = "status"
+
+// checkFinally finally 任务在 dag 结束后按顺序执行, 不参与 dag 编排
+func (p *Pipeline) checkFinally() error {
+	var taskAliasOnly = map[string]bool{}
+	for _, task := range p.Tasks {
+		taskAliasOnly[task.Alias] = true
+	}
+
+	var dagMap = map[string]bool{}
+	for _, node := range p.Dag {
+		dagMap[node.Name] = true
+		for _, need := range node.Needs {
+			dagMap[need] = true
+		}
+	}
+
+	for _, task := range p.Finally {
+		if err := task.Check(p.Contexts); err != nil {
+			return err
+		}
+
+		if task.Type != apistructs.K8sType && task.Type != apistructs.DockerType && task.Type != apistructs.OsType {
+			return fmt.Errorf("finally task alias %v only support [%s %s %s] task type", task.Alias, apistructs.K8sType, apistructs.DockerType, apistructs.OsType)
+		}
+		if task.Matrix != nil {
+			return fmt.Errorf("finally task alias %v not support matrix", task.Alias)
+		}
+		if len(task.Outputs) > 0 {
+			return fmt.Errorf("finally task alias %v not support outputs", task.Alias)
+		}
+
+		if taskAliasOnly[task.Alias] {
+			return fmt.Errorf("task alias %s not only", task.Alias)
+		}
+		taskAliasOnly[task.Alias] = true
+
+		if dagMap[task.Alias] {
+			return fmt.Errorf("finally task alias %v can not described in dag", task.Alias)
+		}
+	}
+
+	for _, task := range p.Tasks {
+		if err := task.pipelinePlaceholderCheck(false); err != nil {
+			return err
+		}
+	}
+	for _, task := range p.Finally {
+		if err := task.pipelinePlaceholderCheck(true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t Task) pipelinePlaceholderCheck(isFinally bool) error {
+	taskYaml, err := yaml.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("task alias %v yaml marshal error %v", t.Alias, err)
+	}
+
+	return placeholder.MatchHolderFromHandler(string(taskYaml), map[placeholder.Type]placeholder.Handler{
+		placeholder.PipelineType: func(holder string, values ...string) error {
+			if !isFinally {
+				return fmt.Errorf("task alias %v not finally task, can not use placeholder %v", t.Alias, holder)
+			}
+			if values[1] != PipelineStatusKey {
+				return fmt.Errorf("task alias %v placeholder %v not support, use ${{ %v.%v }}", t.Alias, holder, placeholder.PipelineType, PipelineStatusKey)
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,237 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pipeline
+
+import (
+	"eventops/apistructs"
+	"eventops/pkg/placeholder"
+	"fmt"
+	"github.com/tidwall/gjson"
+	"gopkg.in/yaml.v3"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const MaxMatrixInstances = 256
+const DefaultMatrixKey = "item"
+
+type Matrix struct {
+	// 每个 key 的取值列表, 实例为所有 key 取值的笛卡尔积
+	Values map[string][]string `yaml:"values,omitempty"`
+	// 上游任务 json 数组类型的出参, 例如 ${{ outputs.build.versions }}
+	// 数组的元素为对象时对象的字段作为 matrix 的 key, 否则使用 key 字段作为 matrix 的 key
+	From        string `yaml:"from,omitempty"`
+	Key         string `yaml:"key,omitempty"`
+	MaxParallel int    `yaml:"maxParallel,omitempty"`
+}
+
+func (m Matrix) GetKey() string {
+	if m.Key == "" {
+		return DefaultMatrixKey
+	}
+	return m.Key
+}
+
+func (m Matrix) check() error {
+	if len(m.Values) == 0 && m.From == "" {
+		return fmt.Errorf("matrix values and from can not both empty")
+	}
+
+	var total = 1
+	for key, values := range m.Values {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("matrix key can not empty")
+		}
+		if len(values) == 0 {
+			return fmt.Errorf("matrix key %v values can not empty", key)
+		}
+		total = total * len(values)
+		if total > MaxMatrixInstances {
+			return fmt.Errorf("matrix instances can not more than %v", MaxMatrixInstances)
+		}
+	}
+
+	if m.From != "" {
+		if placeholder.PhRe.FindString(m.From) != m.From || !strings.HasPrefix(m.From, placeholder.Left+placeholder.OutputType.String()+".") {
+			return fmt.Errorf("matrix from %v should be ${{ outputs.alias.xxx }}", m.From)
+		}
+		if _, ok := m.Values[m.GetKey()]; ok {
+			return fmt.Errorf("matrix key %v already in values", m.GetKey())
+		}
+	}
+
+	if m.MaxParallel < 0 {
+		return fmt.Errorf("matrix maxParallel can not less than 0")
+	}
+	return nil
+}
+
+// Combinations 返回 matrix 所有取值的组合, fromValue 为 from 出参的值
+func (m Matrix) Combinations(fromValue string) ([]map[string]string, error) {
+	var keys []string
+	for key := range m.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var combinations = []map[string]string{{}}
+	for _, key := range keys {
+		var next []map[string]string
+		for _, combination := range combinations {
+			for _, value := range m.Values[key] {
+				next = append(next, mergeMatrixValues(combination, map[string]string{key: value}))
+			}
+		}
+		combinations = next
+	}
+
+	if m.From == "" {
+		return combinations, nil
+	}
+
+	result := gjson.Parse(fromValue)
+	if !result.IsArray() {
+		return nil, fmt.Errorf("matrix from %v value is not json array", m.From)
+	}
+
+	var items []map[string]string
+	for _, element := range result.Array() {
+		var item = map[string]string{}
+		if element.IsObject() {
+			element.ForEach(func(key, value gjson.Result) bool {
+				item[key.String()] = value.String()
+				return true
+			})
+		} else {
+			item[m.GetKey()] = element.String()
+		}
+		items = append(items, item)
+	}
+
+	var next []map[string]string
+	for _, combination := range combinations {
+		for _, item := range items {
+			next = append(next, mergeMatrixValues(combination, item))
+		}
+	}
+	if len(next) > MaxMatrixInstances {
+		return nil, fmt.Errorf("matrix instances can not more than %v", MaxMatrixInstances)
+	}
+	return next, nil
+}
+
+func mergeMatrixValues(values ...map[string]string) map[string]string {
+	var result = map[string]string{}
+	for _, value := range values {
+		for k, v := range value {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+func MatrixInstanceAlias(alias string, index int) string {
+	return fmt.Sprintf("%v-%v", alias, index)
+}
+
+// MatrixInstance 使用 matrix 的一组取值生成实例任务的定义, 任务所有字段中的 matrix 占位符都会被替换
+func (t Task) MatrixInstance(index int, values map[string]string) (*Task, error) {
+	var node yaml.Node
+	if err := node.Encode(t); err != nil {
+		return nil, fmt.Errorf("task alias %v yaml encode error %v", t.Alias, err)
+	}
+	replaceMatrixPlaceholder(&node, &placeholder.ReplaceValue{Matrix: values})
+
+	var instance Task
+	if err := node.Decode(&instance); err != nil {
+		return nil, fmt.Errorf("task alias %v matrix instance yaml decode error %v", t.Alias, err)
+	}
+	instance.Alias = MatrixInstanceAlias(t.Alias, index)
+	instance.Matrix = nil
+	return &instance, nil
+}
+
+// replaceMatrixPlaceholder 只替换标量的值, 替换后的值中有 yaml 的特殊字符也不会改变任务的结构
+func replaceMatrixPlaceholder(node *yaml.Node, replaceValue *placeholder.ReplaceValue) {
+	if node.Kind == yaml.ScalarNode {
+		node.Value = placeholder.ReplacePlaceholder(node.Value, replaceValue, false)
+		return
+	}
+	for _, child := range node.Content {
+		replaceMatrixPlaceholder(child, replaceValue)
+	}
+}
+
+func (t Task) matrixCheck() error {
+	taskYaml, err := yaml.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("task alias %v yaml marshal error %v", t.Alias, err)
+	}
+
+	if t.Matrix == nil {
+		return placeholder.MatchHolderFromHandler(string(taskYaml), map[placeholder.Type]placeholder.Handler{
+			placeholder.MatrixType: func(holder string, values ...string) error {
+				return fmt.Errorf("task alias %v not declare matrix, can not use placeholder %v", t.Alias, holder)
+			},
+		})
+	}
+
+	if t.Type != apistructs.K8sType && t.Type != apistructs.DockerType && t.Type != apistructs.OsType {
+		return fmt.Errorf("task alias %v only [%s %s %s] task type support matrix", t.Alias, apistructs.K8sType, apistructs.DockerType, apistructs.OsType)
+	}
+
+	if err := t.Matrix.check(); err != nil {
+		return fmt.Errorf("task alias %v %v", t.Alias, err)
+	}
+
+	// 出参会汇总成 json 数组, 只支持 env 类型
+	for _, output := range t.Outputs {
+		if output.Type != apistructs.EnvType {
+			return fmt.Errorf("task alias %v matrix task output %v only support %v type", t.Alias, output.Name, apistructs.EnvType)
+		}
+		if output.SetToContext != "" {
+			return fmt.Errorf("task alias %v matrix task output %v not support setToContext", t.Alias, output.Name)
+		}
+	}
+
+	// 使用 from 时 matrix 的 key 在运行时才能确定
+	if t.Matrix.From != "" {
+		return nil
+	}
+	return placeholder.MatchHolderFromHandler(string(taskYaml), map[placeholder.Type]placeholder.Handler{
+		placeholder.MatrixType: func(holder string, values ...string) error {
+			if _, ok := t.Matrix.Values[values[1]]; !ok {
+				return fmt.Errorf("task alias %v matrix not has key %v", t.Alias, values[1])
+			}
+			return nil
+		},
+	})
+}
+
+// checkMatrixAlias 实例任务的别名为 alias-序号, 不能和其他任务的别名冲突
+func checkMatrixAlias(tasks []Task) error {
+	for _, task := range tasks {
+		if task.Matrix == nil {
+			continue
+		}
+		re := regexp.MustCompile(fmt.Sprintf(`^%v-\d+$`, regexp.QuoteMeta(task.Alias)))
+		for _, other := range tasks {
+			if re.MatchString(other.Alias) {
+				return fmt.Errorf("task alias %v conflict with matrix task %v instance alias", other.Alias, task.Alias)
+			}
+		}
+	}
+	return nil
+}
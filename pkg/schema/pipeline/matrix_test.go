@@ -0,0 +1,118 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pipeline
+
+import (
+	"eventops/apistructs"
+	"testing"
+)
+
+func TestMatrixCombinations(t *testing.T) {
+	matrix := Matrix{
+		Values: map[string][]string{
+			"go":     {"1.18", "1.19"},
+			"region": {"a", "b", "c"},
+		},
+	}
+	combinations, err := matrix.Combinations("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(combinations) != 6 {
+		t.Fatalf("combinations len %v, want 6", len(combinations))
+	}
+	if combinations[0]["go"] != "1.18" || combinations[0]["region"] != "a" || combinations[5]["go"] != "1.19" || combinations[5]["region"] != "c" {
+		t.Fatalf("combinations order error %v", combinations)
+	}
+
+	matrix = Matrix{
+		Values: map[string][]string{"go": {"1.18"}},
+		From:   "${{ outputs.build.versions }}",
+	}
+	combinations, err = matrix.Combinations(`["v1", "v2"]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(combinations) != 2 || combinations[1]["item"] != "v2" || combinations[1]["go"] != "1.18" {
+		t.Fatalf("from combinations error %v", combinations)
+	}
+
+	combinations, err = matrix.Combinations(`[{"os": "linux", "arch": "amd64"}]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(combinations) != 1 || combinations[0]["os"] != "linux" || combinations[0]["arch"] != "amd64" {
+		t.Fatalf("from object combinations error %v", combinations)
+	}
+
+	if _, err := matrix.Combinations("v1"); err == nil {
+		t.Fatalf("not json array from value should error")
+	}
+}
+
+func TestMatrixInstance(t *testing.T) {
+	task := Task{
+		Alias:    "test",
+		Type:     apistructs.DockerType,
+		Image:    "golang:${{ matrix.go }}",
+		Commands: []string{"go test ./... -region ${{ matrix.region }} ${{ inputs.args }}"},
+		Matrix: &Matrix{Values: map[string][]string{
+			"go":     {"1.18"},
+			"region": {"a"},
+		}},
+	}
+	if err := task.Check(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	instance, err := task.MatrixInstance(1, map[string]string{"go": "1.18", "region": "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.Alias != "test-1" || instance.Matrix != nil {
+		t.Fatalf("instance alias %v matrix %v", instance.Alias, instance.Matrix)
+	}
+	if instance.Image != "golang:1.18" || instance.Commands[0] != "go test ./... -region a ${{ inputs.args }}" {
+		t.Fatalf("instance image %v commands %v", instance.Image, instance.Commands)
+	}
+	if task.Commands[0] == instance.Commands[0] {
+		t.Fatalf("instance should not change task definition")
+	}
+
+	// 所有字段中的 matrix 占位符都会被替换, 值中的 yaml 特殊字符不影响任务结构
+	task.Inputs = []Input{{Name: "region", Value: "${{ matrix.region }}"}}
+	task.Docker = &Docker{Env: map[string]string{"GO_VERSION": "${{ matrix.go }}"}}
+	instance, err = task.MatrixInstance(2, map[string]string{"go": "1.18", "region": "a: [b]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.Inputs[0].Value != "a: [b]" || instance.Docker.Env["GO_VERSION"] != "1.18" {
+		t.Fatalf("instance inputs %v docker %v", instance.Inputs, instance.Docker)
+	}
+	if task.Inputs[0].Value != "${{ matrix.region }}" || task.Docker.Env["GO_VERSION"] != "${{ matrix.go }}" {
+		t.Fatalf("instance should not change task definition")
+	}
+
+	task.Commands = []string{"echo ${{ matrix.os }}"}
+	if err := task.Check(nil); err == nil {
+		t.Fatalf("undeclared matrix key should check error")
+	}
+
+	task.Matrix = nil
+	task.Image = "golang"
+	task.Commands = []string{"echo ${{ matrix.go }}"}
+	if err := task.Check(nil); err == nil {
+		t.Fatalf("task without matrix should not use matrix placeholder")
+	}
+}
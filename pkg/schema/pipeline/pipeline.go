@@ -25,11 +25,14 @@ import (
 type Pipeline struct {
 	Version          string           `yaml:"version,omitempty"`
 	Name             string           `yaml:"name,omitempty"`
+	Timeout          int64            `yaml:"timeout,omitempty"`
+	Concurrency      *Concurrency     `yaml:"concurrency,omitempty"`
 	ActuatorSelector ActuatorSelector `yaml:"actuatorSelector,omitempty"`
 	Inputs           []Input          `yaml:"inputs,omitempty"`
 	Contexts         []Context        `yaml:"contexts,omitempty"`
 	Dag              Dag              `yaml:"dag,omitempty"`
 	Tasks            []Task           `yaml:"tasks,omitempty"`
+	Finally          []Task           `yaml:"finally,omitempty"`
 	Outputs          []Output         `yaml:"outputs,omitempty"`
 }
 
@@ -57,7 +60,7 @@ func (p *Pipeline) GetTaskByAlias(alias string) (pipelineTask *Task) {
 	return nil
 }
 
-func (p *Pipeline) Mutating(pipelineTypeTaskDefinitionMap map[string]Pipeline) error {
+func (p *Pipeline) Mutating(pipelineTypeTaskDefinitionMap map[string]Pipeline, defaultTaskTimeout int64) error {
 	err := p.pipelineTypeTaskOutputTypeMutating(pipelineTypeTaskDefinitionMap)
 	if err != nil {
 		return err
@@ -68,7 +71,7 @@ func (p *Pipeline) Mutating(pipelineTypeTaskDefinitionMap map[string]Pipeline) e
 		return err
 	}
 
-	p.taskTimeoutMutating()
+	p.taskTimeoutMutating(defaultTaskTimeout)
 	return nil
 }
 
@@ -154,14 +157,20 @@ func (p *Pipeline) pipelineOutputTypeMutating() error {
 	return nil
 }
 
-func (p *Pipeline) taskTimeoutMutating() {
+func (p *Pipeline) taskTimeoutMutating(defaultTaskTimeout int64) {
 
 	for index, task := range p.Tasks {
 		if task.Type == apistructs.PipeType {
 			continue
 		}
 		if task.Timeout <= 0 {
-			p.Tasks[index].Timeout = 3600
+			p.Tasks[index].Timeout = defaultTaskTimeout
+		}
+	}
+
+	for index, task := range p.Finally {
+		if task.Timeout <= 0 {
+			p.Finally[index].Timeout = defaultTaskTimeout
 		}
 	}
 }
@@ -187,6 +196,16 @@ func (p *Pipeline) checkFormat() error {
 		return fmt.Errorf("pipeline name can not empty")
 	}
 
+	if p.Timeout < 0 {
+		return fmt.Errorf("pipeline timeout can not less than 0")
+	}
+
+	if p.Concurrency != nil {
+		if err := p.Concurrency.Check(); err != nil {
+			return err
+		}
+	}
+
 	if err := p.ActuatorSelector.check(); err != nil {
 		return err
 	}
@@ -211,6 +230,10 @@ func (p *Pipeline) checkFormat() error {
 		return err
 	}
 
+	if err := p.checkFinally(); err != nil {
+		return err
+	}
+
 	if err := p.checkOutput(); err != nil {
 		return err
 	}
@@ -291,7 +314,7 @@ func (p *Pipeline) checkTasks() error {
 			taskAliasOnly[task.Alias] = true
 		}
 	}
-	return nil
+	return checkMatrixAlias(p.Tasks)
 }
 
 func (p *Pipeline) checkDagTask() error {
@@ -513,22 +536,27 @@ func (p *Pipeline) checkOutputsPlaceholder(dagInfo *dag.Dag) error {
 			return fmt.Errorf("task alias %v yaml marshal error %v", task.Alias, err)
 		}
 
+		// 校验占位符中的任务是否是 dag 中之前可以被执行到的任务
+		allNeedTasks := dagInfo.GetAllNeedsTask(task.Alias)
+		var checkNeedTask = func(placeholder string, taskAlias string) error {
+			for _, needTask := range allNeedTasks {
+				if taskAlias == needTask {
+					return nil
+				}
+			}
+			return fmt.Errorf("this task (alias %v) will not be scheduled before this placeholder %v task (alias %v)", task.Alias, placeholder, taskAlias)
+		}
+
 		err = placeholder.MatchHolderFromHandler(string(taskYaml), map[placeholder.Type]placeholder.Handler{
+			placeholder.TaskType: func(placeholder string, values ...string) error {
+				return checkNeedTask(placeholder, values[1])
+			},
 			placeholder.OutputType: func(placeholder string, values ...string) error {
 				taskAlias := values[1]
 				taskOutputName := values[2]
 
-				// 校验出参占位符中的任务是否是 dag 中之前可以被执行到的任务
-				var inNeedTask = false
-				allNeedTasks := dagInfo.GetAllNeedsTask(task.Alias)
-				for _, needTask := range allNeedTasks {
-					if taskAlias == needTask {
-						inNeedTask = true
-						break
-					}
-				}
-				if !inNeedTask {
-					return fmt.Errorf("this task (alias %v) will not be scheduled before this placeholder %v task (alias %v)", task.Alias, placeholder, taskAlias)
+				if err := checkNeedTask(placeholder, taskAlias); err != nil {
+					return err
 				}
 
 				// 校验出参是否在当前 pipeline 中定义
@@ -560,8 +588,10 @@ func (p *Pipeline) checkPlaceholderExist(pipelineContent string) error {
 		return fmt.Sprintf("%v-%v", alias, name)
 	}
 
+	var taskAliasMap = map[string]bool{}
 	var taskAliasOutputMap = map[string]Output{}
 	for _, task := range p.Tasks {
+		taskAliasMap[task.Alias] = true
 		for _, output := range task.Outputs {
 			taskAliasOutputMap[mapKeyBuild(task.Alias, output.Name)] = output
 		}
@@ -610,6 +640,15 @@ func (p *Pipeline) checkPlaceholderExist(pipelineContent string) error {
 			}
 			return nil
 		},
+		placeholder.TaskType: func(holder string, values ...string) error {
+			if !taskAliasMap[values[1]] {
+				return fmt.Errorf("pipeline not has task %v", values[1])
+			}
+			if values[2] != TaskStatusKey {
+				return fmt.Errorf("placeholder %v not support, use ${{ %v.alias.%v }}", holder, placeholder.TaskType, TaskStatusKey)
+			}
+			return nil
+		},
 	})
 	if err != nil {
 		return err
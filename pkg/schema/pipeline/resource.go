@@ -13,6 +13,11 @@
 
 package pipeline
 
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
 type Resources struct {
 	Limit   *Limit   `yaml:"limit,omitempty"`
 	Request *Request `yaml:"request,omitempty"`
@@ -27,3 +32,59 @@ type Request struct {
 	Cpu string `yaml:"cpu,omitempty"`
 	Mem string `yaml:"mem,omitempty"`
 }
+
+// Quantities 解析 cpu 和 mem, cpu 支持 0.5 或 500m, mem 支持 512Mi 或 1Gi 这样的写法
+func (l Limit) Quantities() (cpu resource.Quantity, mem resource.Quantity, err error) {
+	return parseQuantities("limit", l.Cpu, l.Mem)
+}
+
+func (r Request) Quantities() (cpu resource.Quantity, mem resource.Quantity, err error) {
+	return parseQuantities("request", r.Cpu, r.Mem)
+}
+
+func parseQuantities(kind string, cpuValue string, memValue string) (cpu resource.Quantity, mem resource.Quantity, err error) {
+	cpu, err = resource.ParseQuantity(cpuValue)
+	if err != nil {
+		return cpu, mem, fmt.Errorf("resources %v cpu %v parse error: %v", kind, cpuValue, err)
+	}
+	if cpu.Sign() <= 0 {
+		return cpu, mem, fmt.Errorf("resources %v cpu %v should greater than 0", kind, cpuValue)
+	}
+
+	mem, err = resource.ParseQuantity(memValue)
+	if err != nil {
+		return cpu, mem, fmt.Errorf("resources %v mem %v parse error: %v", kind, memValue, err)
+	}
+	if mem.Sign() <= 0 {
+		return cpu, mem, fmt.Errorf("resources %v mem %v should greater than 0", kind, memValue)
+	}
+	return cpu, mem, nil
+}
+
+func (r Resources) check() error {
+	var limitCpu, limitMem resource.Quantity
+	if r.Limit != nil {
+		var err error
+		limitCpu, limitMem, err = r.Limit.Quantities()
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.Request != nil {
+		requestCpu, requestMem, err := r.Request.Quantities()
+		if err != nil {
+			return err
+		}
+
+		if r.Limit != nil {
+			if requestCpu.Cmp(limitCpu) > 0 {
+				return fmt.Errorf("resources request cpu %v can not greater than limit cpu %v", r.Request.Cpu, r.Limit.Cpu)
+			}
+			if requestMem.Cmp(limitMem) > 0 {
+				return fmt.Errorf("resources request mem %v can not greater than limit mem %v", r.Request.Mem, r.Limit.Mem)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pipeline
+
+import (
+	"testing"
+)
+
+func TestResourcesCheck(t *testing.T) {
+	valid := Resources{
+		Limit:   &Limit{Cpu: "1", Mem: "1Gi"},
+		Request: &Request{Cpu: "500m", Mem: "512Mi"},
+	}
+	if err := valid.check(); err != nil {
+		t.Fatal(err)
+	}
+
+	cpu, mem, err := valid.Request.Quantities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cpu.MilliValue() != 500 || mem.Value() != 512*1024*1024 {
+		t.Fatalf("request cpu %v mem %v parse error", cpu.String(), mem.String())
+	}
+
+	for _, invalid := range []Resources{
+		{Limit: &Limit{Cpu: "one", Mem: "1Gi"}},
+		{Limit: &Limit{Cpu: "1", Mem: "1G1"}},
+		{Request: &Request{Cpu: "0", Mem: "1Gi"}},
+		{Limit: &Limit{Cpu: "1", Mem: "1Gi"}, Request: &Request{Cpu: "2", Mem: "1Gi"}},
+		{Limit: &Limit{Cpu: "1", Mem: "512Mi"}, Request: &Request{Cpu: "1", Mem: "1Gi"}},
+	} {
+		if err := invalid.check(); err == nil {
+			t.Fatalf("resources %v check should error", invalid)
+		}
+	}
+}
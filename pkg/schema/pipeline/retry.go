@@ -0,0 +1,84 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pipeline
+
+import (
+	"eventops/apistructs"
+	"fmt"
+	"math"
+	"time"
+)
+
+var RetryableTaskStatuses = []apistructs.TaskStatus{apistructs.FailedTaskStatus, apistructs.TimeoutTaskStatus, apistructs.UnKnowTaskStatus, apistructs.ErrorTaskStatus}
+var DefaultRetryTaskStatuses = []apistructs.TaskStatus{apistructs.FailedTaskStatus, apistructs.UnKnowTaskStatus, apistructs.ErrorTaskStatus}
+
+type Retry struct {
+	MaxAttempts int                     `yaml:"maxAttempts,omitempty"`
+	Interval    int64                   `yaml:"interval,omitempty"`
+	Backoff     float64                 `yaml:"backoff,omitempty"`
+	On          []apistructs.TaskStatus `yaml:"on,omitempty"`
+}
+
+func (r Retry) check() error {
+	if r.MaxAttempts <= 1 {
+		return fmt.Errorf("retry maxAttempts should be greater than 1")
+	}
+
+	if r.Interval < 0 {
+		return fmt.Errorf("retry interval can not less than 0")
+	}
+
+	if r.Backoff != 0 && r.Backoff < 1 {
+		return fmt.Errorf("retry backoff can not less than 1")
+	}
+
+	for _, status := range r.On {
+		var find = false
+		for _, retryableStatus := range RetryableTaskStatuses {
+			if status == retryableStatus {
+				find = true
+				break
+			}
+		}
+		if !find {
+			return fmt.Errorf("retry on status %v not support, use %v", status, RetryableTaskStatuses)
+		}
+	}
+	return nil
+}
+
+func (r Retry) IsRetryable(status apistructs.TaskStatus) bool {
+	var statuses = r.On
+	if len(statuses) == 0 {
+		statuses = DefaultRetryTaskStatuses
+	}
+
+	for _, retryStatus := range statuses {
+		if retryStatus == status {
+			return true
+		}
+	}
+	return false
+}
+
+// GetInterval attempt 从 1 开始, 返回第 attempt 次执行失败后需要等待的时间
+func (r Retry) GetInterval(attempt int) time.Duration {
+	var backoff = r.Backoff
+	if backoff == 0 {
+		backoff = 1
+	}
+
+	interval := float64(r.Interval) * math.Pow(backoff, float64(attempt-1))
+	return time.Duration(interval) * time.Second
+}
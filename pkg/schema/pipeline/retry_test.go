@@ -0,0 +1,53 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package pipeline
+
+import (
+	"eventops/apistructs"
+	"testing"
+	"time"
+)
+
+func TestRetryGetInterval(t *testing.T) {
+	var testData = []struct {
+		retry   Retry
+		attempt int
+		want    time.Duration
+	}{
+		{Retry{Interval: 30}, 1, 30 * time.Second},
+		{Retry{Interval: 30}, 3, 30 * time.Second},
+		{Retry{Interval: 30, Backoff: 2}, 1, 30 * time.Second},
+		{Retry{Interval: 30, Backoff: 2}, 2, 60 * time.Second},
+		{Retry{Interval: 30, Backoff: 2}, 3, 120 * time.Second},
+		{Retry{Interval: 10, Backoff: 1.5}, 2, 15 * time.Second},
+		{Retry{}, 2, 0},
+	}
+	for _, data := range testData {
+		if got := data.retry.GetInterval(data.attempt); got != data.want {
+			t.Fatalf("retry %v attempt %v interval %v, want %v", data.retry, data.attempt, got, data.want)
+		}
+	}
+}
+
+func TestRetryIsRetryable(t *testing.T) {
+	var defaultRetry = Retry{MaxAttempts: 2}
+	if !defaultRetry.IsRetryable(apistructs.FailedTaskStatus) || defaultRetry.IsRetryable(apistructs.TimeoutTaskStatus) {
+		t.Fatalf("default retry statuses error")
+	}
+
+	var timeoutRetry = Retry{MaxAttempts: 2, On: []apistructs.TaskStatus{apistructs.TimeoutTaskStatus}}
+	if !timeoutRetry.IsRetryable(apistructs.TimeoutTaskStatus) || timeoutRetry.IsRetryable(apistructs.FailedTaskStatus) {
+		t.Fatalf("retry on statuses error")
+	}
+}
@@ -15,6 +15,8 @@ package pipeline
 
 import (
 	"eventops/apistructs"
+	"eventops/pkg/condition"
+	"eventops/pkg/placeholder"
 	"fmt"
 	"strings"
 )
@@ -22,6 +24,9 @@ import (
 const ImageCreaterNameSplitWord = "/"
 const ImageNameVersionSplitWord = ":"
 
+// TaskStatusKey ${{ tasks.alias.status }} 为任务的状态
+const TaskStatusKey = "status"
+
 type Task struct {
 	Image            string              `yaml:"image,omitempty"`
 	Alias            string              `yaml:"alias,omitempty"`
@@ -32,6 +37,14 @@ type Task struct {
 	Outputs          []Output            `yaml:"outputs,omitempty"`
 	Timeout          int64               `yaml:"timeout,omitempty"`
 	Resources        *Resources          `yaml:"resources,omitempty"`
+	Docker           *Docker             `yaml:"docker,omitempty"`
+	When             string              `yaml:"when,omitempty"`
+	Retry            *Retry              `yaml:"retry,omitempty"`
+	Caches           []Cache             `yaml:"caches,omitempty"`
+	Approval         *Approval           `yaml:"approval,omitempty"`
+	Matrix           *Matrix             `yaml:"matrix,omitempty"`
+	// AllowFailure 为 true 时任务失败不会停止流水线, 下游任务继续执行
+	AllowFailure bool `yaml:"allowFailure,omitempty"`
 }
 
 func (t Task) GetPipelineVersion() string {
@@ -98,7 +111,23 @@ func (t Task) Check(pipelineContexts []Context) error {
 	}
 
 	if !t.Type.Check() {
-		return fmt.Errorf("use [%s %s %s %s] these task type", apistructs.K8sType, apistructs.DockerType, apistructs.OsType, apistructs.PipeType)
+		return fmt.Errorf("use %v these task type", apistructs.TaskTypeList)
+	}
+
+	if err := t.matrixCheck(); err != nil {
+		return err
+	}
+
+	if t.AllowFailure && t.Type == apistructs.PipeType {
+		return fmt.Errorf("task alias %v [%s] task type not support allowFailure", t.Alias, apistructs.PipeType)
+	}
+
+	if t.Type == apistructs.ApprovalType {
+		return t.approvalCheck()
+	}
+
+	if t.Approval != nil {
+		return fmt.Errorf("task alias %v only [%s] task type support approval", t.Alias, apistructs.ApprovalType)
 	}
 
 	if t.Type != apistructs.OsType && len(t.Image) == 0 {
@@ -125,6 +154,109 @@ func (t Task) Check(pipelineContexts []Context) error {
 		return err
 	}
 
+	if err := t.dockerCheck(); err != nil {
+		return err
+	}
+
+	if err := t.whenCheck(); err != nil {
+		return err
+	}
+
+	if err := t.cacheCheck(); err != nil {
+		return err
+	}
+
+	if t.Retry != nil {
+		if t.Type == apistructs.PipeType {
+			return fmt.Errorf("task alias %v [%s] task type not support retry", t.Alias, apistructs.PipeType)
+		}
+		if err := t.Retry.check(); err != nil {
+			return fmt.Errorf("task alias %v %v", t.Alias, err)
+		}
+	}
+
+	return nil
+}
+
+// approvalCheck approval 类型的任务不会在执行器上运行, 只等待用户审批
+func (t Task) approvalCheck() error {
+	if t.Image != "" || len(t.Commands) > 0 {
+		return fmt.Errorf("task alias %v [%s] task type not support image and commands", t.Alias, apistructs.ApprovalType)
+	}
+
+	if len(t.Inputs) > 0 || len(t.Outputs) > 0 {
+		return fmt.Errorf("task alias %v [%s] task type not support inputs and outputs", t.Alias, apistructs.ApprovalType)
+	}
+
+	if t.Resources != nil || t.Docker != nil || t.Retry != nil || len(t.Caches) > 0 {
+		return fmt.Errorf("task alias %v [%s] task type not support resources, docker, retry and caches", t.Alias, apistructs.ApprovalType)
+	}
+
+	if len(t.ActuatorSelector.Tags) > 0 {
+		return fmt.Errorf("task alias %v [%s] task type not support actuatorSelector", t.Alias, apistructs.ApprovalType)
+	}
+
+	if err := t.whenCheck(); err != nil {
+		return err
+	}
+
+	if t.Approval != nil {
+		if err := t.Approval.check(); err != nil {
+			return fmt.Errorf("task alias %v %v", t.Alias, err)
+		}
+	}
+	return nil
+}
+
+func (t Task) dockerCheck() error {
+	if t.Docker == nil {
+		return nil
+	}
+
+	if t.Type != apistructs.DockerType {
+		return fmt.Errorf("task alias %v only [%s] task type support docker", t.Alias, apistructs.DockerType)
+	}
+	if err := t.Docker.Check(); err != nil {
+		return fmt.Errorf("task alias %v %v", t.Alias, err)
+	}
+	return nil
+}
+
+func (t Task) cacheCheck() error {
+	if len(t.Caches) == 0 {
+		return nil
+	}
+
+	if t.Type == apistructs.PipeType {
+		return fmt.Errorf("task alias %v [%s] task type not support caches", t.Alias, apistructs.PipeType)
+	}
+
+	for _, cache := range t.Caches {
+		if err := cache.check(); err != nil {
+			return fmt.Errorf("task alias %v %v", t.Alias, err)
+		}
+	}
+	return nil
+}
+
+func (t Task) whenCheck() error {
+	if t.When == "" {
+		return nil
+	}
+
+	if err := condition.Check(t.When); err != nil {
+		return fmt.Errorf("task alias %v when check error %v", t.Alias, err)
+	}
+
+	// when 中只能使用 inputs contexts outputs 占位符
+	err := placeholder.MatchHolderFromHandler(t.When, map[placeholder.Type]placeholder.Handler{
+		placeholder.RandomType: func(holder string, values ...string) error {
+			return fmt.Errorf("task alias %v when not support placeholder %v", t.Alias, holder)
+		},
+	})
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -195,7 +327,7 @@ func (t Task) resourceCheck() error {
 			return fmt.Errorf("resources limit cpu can not empty")
 		}
 		if t.Resources.Limit.Mem == "" {
-			return fmt.Errorf("resources limit cpu can not empty")
+			return fmt.Errorf("resources limit mem can not empty")
 		}
 	}
 
@@ -204,9 +336,9 @@ func (t Task) resourceCheck() error {
 			return fmt.Errorf("resources request cpu can not empty")
 		}
 		if t.Resources.Request.Mem == "" {
-			return fmt.Errorf("resources request cpu can not empty")
+			return fmt.Errorf("resources request mem can not empty")
 		}
 	}
 
-	return nil
+	return t.Resources.check()
 }
@@ -50,28 +50,33 @@ var eventSendCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		err = sendEvent(applyUser, content)
+		result, err := sendEvent(applyUser, content)
 		if err != nil {
 			fmt.Printf("send event error: %v \n", err)
 			os.Exit(1)
 		}
+		if result.Duplicate {
+			fmt.Printf("event is duplicate, id: %v \n", result.Id)
+		} else {
+			fmt.Printf("event id: %v \n", result.Id)
+		}
 	},
 }
 
-type Resp struct {
+type SendEventResp struct {
 	Status int
 	Msg    string
-	Data   interface{}
+	Data   apistructs.SendEventResponse
 }
 
-func sendEvent(user *conf.UserInfo, content []byte) error {
+func sendEvent(user *conf.UserInfo, content []byte) (*apistructs.SendEventResponse, error) {
 	var eventInfo apistructs.Event
 	err := yaml.Unmarshal(content, &eventInfo)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var resp Resp
+	var resp SendEventResp
 	err = gout.
 		POST(fmt.Sprintf("%s/%s", user.Server, "api/event/send")).
 		SetHeader(gout.H{"sid": fmt.Sprintf("%x", time.Now().UnixNano()), token.AuthHeader: token.BuildTokenHeaderValue(user.Token)}).
@@ -79,12 +84,12 @@ func sendEvent(user *conf.UserInfo, content []byte) error {
 		BindJSON(&resp).
 		Do()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if resp.Status != 200 {
-		return fmt.Errorf("send event error status: %v, msg: %s", resp.Status, resp.Msg)
+		return nil, fmt.Errorf("send event error status: %v, msg: %s", resp.Status, resp.Msg)
 	}
-	return nil
+	return &resp.Data, nil
 }
 
 func BuildEventCmd() *cobra.Command {
@@ -22,6 +22,7 @@ import (
 	"eventops/tools/eoctl/register"
 	"eventops/tools/eoctl/runtime"
 	"eventops/tools/eoctl/trigger"
+	"eventops/tools/eoctl/webhook"
 	"fmt"
 	"github.com/spf13/cobra"
 	"os"
@@ -42,6 +43,7 @@ func Execute() {
 	rootCmd.AddCommand(actuator.BuildActuatorCmd())
 	rootCmd.AddCommand(event.BuildEventCmd())
 	rootCmd.AddCommand(runtime.BuildRuntimeCmd())
+	rootCmd.AddCommand(webhook.BuildWebhookCmd())
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -22,7 +22,10 @@ import (
 	"fmt"
 	"github.com/guonaihong/gout"
 	"github.com/spf13/cobra"
+	"io"
+	"net/http"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -124,6 +127,167 @@ var runtimeCancelCmd = &cobra.Command{
 	},
 }
 
+var runtimeCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Clean pipeline runtime actuator resources",
+	Long:  `You can use this command to clean pipeline runtime resources on actuators`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if pipelineRuntimeId == "" {
+			fmt.Println("runtimeId cannot be empty")
+			os.Exit(1)
+		}
+
+		cleanUser := login.GetEditUserInfo()
+		result, err := CleanPipelineRuntime(cleanUser)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+
+		resultJson, err := json.Marshal(result)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(string(resultJson))
+	},
+}
+
+var runtimeRerunCmd = &cobra.Command{
+	Use:   "rerun",
+	Short: "Rerun pipeline runtime",
+	Long:  `You can use this command to rerun a finished pipeline runtime, or resume it from the failed task`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if pipelineRuntimeId == "" {
+			fmt.Println("runtimeId cannot be empty")
+			os.Exit(1)
+		}
+
+		rerunUser := login.GetEditUserInfo()
+		result, err := RerunPipelineRuntime(rerunUser)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+
+		resultJson, err := json.Marshal(result)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(string(resultJson))
+	},
+}
+
+var runtimePauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Pause pipeline runtime",
+	Long:  `You can use this command to pause a running pipeline runtime, running tasks will finish but no new task will be created`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if pipelineRuntimeId == "" {
+			fmt.Println("runtimeId cannot be empty")
+			os.Exit(1)
+		}
+
+		pauseUser := login.GetEditUserInfo()
+		result, err := PausePipelineRuntime(pauseUser, "pause")
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+
+		resultJson, err := json.Marshal(result)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(string(resultJson))
+	},
+}
+
+var runtimeResumeCmd = &cobra.Command{
+	Use:   "resume",
+	Short: "Resume pipeline runtime",
+	Long:  `You can use this command to resume a paused pipeline runtime`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if pipelineRuntimeId == "" {
+			fmt.Println("runtimeId cannot be empty")
+			os.Exit(1)
+		}
+
+		resumeUser := login.GetEditUserInfo()
+		result, err := PausePipelineRuntime(resumeUser, "resume")
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+
+		resultJson, err := json.Marshal(result)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(string(resultJson))
+	},
+}
+
+var runtimeLogsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Print pipeline runtime task logs",
+	Long:  `You can use this command to print pipeline runtime task logs`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if pipelineRuntimeId == "" {
+			fmt.Println("runtimeId cannot be empty")
+			os.Exit(1)
+		}
+		if logsTaskId == "" {
+			fmt.Println("taskId cannot be empty")
+			os.Exit(1)
+		}
+
+		logsUser := login.GetEditUserInfo()
+		if err := PrintPipelineRuntimeTaskLogs(logsUser, os.Stdout); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+var runtimeApproveCmd = &cobra.Command{
+	Use:   "approve",
+	Short: "Approve or reject pipeline runtime approval task",
+	Long:  `You can use this command to approve or reject pipeline runtime approval task`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if pipelineRuntimeId == "" {
+			fmt.Println("runtimeId cannot be empty")
+			os.Exit(1)
+		}
+		if approveTaskId == "" {
+			fmt.Println("taskId cannot be empty")
+			os.Exit(1)
+		}
+
+		approveUser := login.GetEditUserInfo()
+		result, err := ApprovePipelineRuntimeTask(approveUser)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+
+		resultJson, err := json.Marshal(result)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println(string(resultJson))
+	},
+}
+
 type ListResp struct {
 	Status int
 	Msg    string
@@ -139,6 +303,8 @@ var TriggerDefinitionName string
 var PipelineDefinitionName string
 var PipelineDefinitionVersion string
 var PipelineDefinitionCreater string
+var Status string
+var ConcurrencyGroup string
 var Top string
 
 func ListPipelineRuntimes(user *conf.UserInfo) ([]apistructs.Pipeline, error) {
@@ -153,6 +319,8 @@ func ListPipelineRuntimes(user *conf.UserInfo) ([]apistructs.Pipeline, error) {
 			"pipelineDefinitionName":    PipelineDefinitionName,
 			"pipelineDefinitionVersion": PipelineDefinitionVersion,
 			"pipelineDefinitionCreater": PipelineDefinitionCreater,
+			"status":                    Status,
+			"concurrencyGroup":          ConcurrencyGroup,
 			"top":                       Top,
 		}).
 		SetHeader(gout.H{"sid": fmt.Sprintf("%x", time.Now().UnixNano()), token.AuthHeader: token.BuildTokenHeaderValue(user.Token)}).
@@ -216,11 +384,158 @@ func CancelPipelineRuntimeDetail(user *conf.UserInfo) (string, error) {
 	return resp.Data, nil
 }
 
+type CleanResp struct {
+	Status int
+	Msg    string
+	Data   string
+}
+
+func CleanPipelineRuntime(user *conf.UserInfo) (string, error) {
+	var resp CleanResp
+	err := gout.
+		POST(fmt.Sprintf("%s/%s", user.Server, fmt.Sprintf("api/pipeline/%v/clean", pipelineRuntimeId))).
+		SetHeader(gout.H{"sid": fmt.Sprintf("%x", time.Now().UnixNano()), token.AuthHeader: token.BuildTokenHeaderValue(user.Token)}).
+		BindJSON(&resp).
+		Do()
+	if err != nil {
+		return "", err
+	}
+	if resp.Status != 200 {
+		return "", fmt.Errorf("failed clean pipeline runtime status: %v, msg: %s", resp.Status, resp.Msg)
+	}
+
+	return resp.Data, nil
+}
+
+type PauseResp struct {
+	Status int
+	Msg    string
+	Data   string
+}
+
+// PausePipelineRuntime action 为 pause 或者 resume
+func PausePipelineRuntime(user *conf.UserInfo, action string) (string, error) {
+	var resp PauseResp
+	err := gout.
+		POST(fmt.Sprintf("%s/%s", user.Server, fmt.Sprintf("api/pipeline/%v/%v", pipelineRuntimeId, action))).
+		SetHeader(gout.H{"sid": fmt.Sprintf("%x", time.Now().UnixNano()), token.AuthHeader: token.BuildTokenHeaderValue(user.Token)}).
+		BindJSON(&resp).
+		Do()
+	if err != nil {
+		return "", err
+	}
+	if resp.Status != 200 {
+		return "", fmt.Errorf("failed %v pipeline runtime status: %v, msg: %s", action, resp.Status, resp.Msg)
+	}
+
+	return resp.Data, nil
+}
+
+var rerunFromFailed bool
+
+type RerunResp struct {
+	Status int
+	Msg    string
+	Data   apistructs.Pipeline
+}
+
+func RerunPipelineRuntime(user *conf.UserInfo) (*apistructs.Pipeline, error) {
+	var resp RerunResp
+	err := gout.
+		POST(fmt.Sprintf("%s/%s", user.Server, fmt.Sprintf("api/pipeline/%v/rerun", pipelineRuntimeId))).
+		SetHeader(gout.H{"sid": fmt.Sprintf("%x", time.Now().UnixNano()), token.AuthHeader: token.BuildTokenHeaderValue(user.Token)}).
+		SetJSON(apistructs.RerunPipelineBody{FromFailed: rerunFromFailed}).
+		BindJSON(&resp).
+		Do()
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != 200 {
+		return nil, fmt.Errorf("failed rerun pipeline runtime status: %v, msg: %s", resp.Status, resp.Msg)
+	}
+
+	return &resp.Data, nil
+}
+
+var logsTaskId string
+var logsFollow bool
+
+type LogsResp struct {
+	Status int
+	Msg    string
+}
+
+// PrintPipelineRuntimeTaskLogs 日志是持续输出的, 不能使用 gout 一次性读取
+func PrintPipelineRuntimeTaskLogs(user *conf.UserInfo, writer io.Writer) error {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/%s", user.Server, fmt.Sprintf("api/pipeline/%v/task/%v/logs?follow=%v", pipelineRuntimeId, logsTaskId, logsFollow)), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("sid", fmt.Sprintf("%x", time.Now().UnixNano()))
+	req.Header.Set(token.AuthHeader, token.BuildTokenHeaderValue(user.Token))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		var errResp LogsResp
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+			return err
+		}
+		return fmt.Errorf("failed get pipeline runtime task logs status: %v, msg: %s", errResp.Status, errResp.Msg)
+	}
+
+	_, err = io.Copy(writer, resp.Body)
+	return err
+}
+
+var approveTaskId string
+var approveReject bool
+var approveComment string
+
+type ApproveResp struct {
+	Status int
+	Msg    string
+	Data   string
+}
+
+func ApprovePipelineRuntimeTask(user *conf.UserInfo) (string, error) {
+	var action = "approve"
+	if approveReject {
+		action = "reject"
+	}
+
+	var resp ApproveResp
+	err := gout.
+		POST(fmt.Sprintf("%s/%s", user.Server, fmt.Sprintf("api/pipeline/%v/task/%v/%v", pipelineRuntimeId, approveTaskId, action))).
+		SetHeader(gout.H{"sid": fmt.Sprintf("%x", time.Now().UnixNano()), token.AuthHeader: token.BuildTokenHeaderValue(user.Token)}).
+		SetJSON(apistructs.ApproveTaskBody{Comment: approveComment}).
+		BindJSON(&resp).
+		Do()
+	if err != nil {
+		return "", err
+	}
+	if resp.Status != 200 {
+		return "", fmt.Errorf("failed %v pipeline runtime task status: %v, msg: %s", action, resp.Status, resp.Msg)
+	}
+
+	return resp.Data, nil
+}
+
 func BuildRuntimeCmd() *cobra.Command {
 	login.BindUserAndServerFlag(runtimeCmd)
 	login.BindUserAndServerFlag(runtimeListCmd)
 	login.BindUserAndServerFlag(runtimeGetDetailCmd)
 	login.BindUserAndServerFlag(runtimeCancelCmd)
+	login.BindUserAndServerFlag(runtimeCleanCmd)
+	login.BindUserAndServerFlag(runtimeRerunCmd)
+	login.BindUserAndServerFlag(runtimePauseCmd)
+	login.BindUserAndServerFlag(runtimeResumeCmd)
+	login.BindUserAndServerFlag(runtimeLogsCmd)
+	login.BindUserAndServerFlag(runtimeApproveCmd)
 
 	runtimeListCmd.PersistentFlags().StringVarP(&EventName, "en", "", "", "list pipeline runtime by eventName")
 	runtimeListCmd.PersistentFlags().StringVarP(&EventVersion, "ev", "", "", "list pipeline runtime by eventVersion")
@@ -231,14 +546,40 @@ func BuildRuntimeCmd() *cobra.Command {
 	runtimeListCmd.PersistentFlags().StringVarP(&PipelineDefinitionName, "pdn", "", "", "list pipeline runtime by pipelineDefinitionName")
 	runtimeListCmd.PersistentFlags().StringVarP(&PipelineDefinitionVersion, "pdv", "", "", "list pipeline runtime by pipelineDefinitionVersion")
 	runtimeListCmd.PersistentFlags().StringVarP(&PipelineDefinitionCreater, "pdc", "", "", "list pipeline runtime by pipelineDefinitionCreater")
+	runtimeListCmd.PersistentFlags().StringVarP(&Status, "status", "", "", "list pipeline runtime by status, e.g. running queued paused")
+	runtimeListCmd.PersistentFlags().StringVarP(&ConcurrencyGroup, "group", "", "", "list pipeline runtime by concurrency group")
 	runtimeListCmd.PersistentFlags().StringVarP(&Top, "top", "", "20", "limit pipeline runtime result num. top max 100. default = 20")
 
 	runtimeGetDetailCmd.PersistentFlags().StringVarP(&pipelineRuntimeId, "id", "", "", "get pipeline runtime detail by id")
 
 	runtimeCancelCmd.PersistentFlags().StringVarP(&pipelineRuntimeId, "id", "", "", "cancel pipeline runtime by id")
 
+	runtimeCleanCmd.PersistentFlags().StringVarP(&pipelineRuntimeId, "id", "", "", "clean pipeline runtime actuator resources by id")
+
+	runtimeRerunCmd.PersistentFlags().StringVarP(&pipelineRuntimeId, "id", "", "", "rerun pipeline runtime by id")
+	runtimeRerunCmd.PersistentFlags().BoolVarP(&rerunFromFailed, "from-failed", "", false, "only rerun the failed tasks and their descendants")
+
+	runtimePauseCmd.PersistentFlags().StringVarP(&pipelineRuntimeId, "id", "", "", "pause pipeline runtime by id")
+
+	runtimeResumeCmd.PersistentFlags().StringVarP(&pipelineRuntimeId, "id", "", "", "resume pipeline runtime by id")
+
+	runtimeLogsCmd.PersistentFlags().StringVarP(&pipelineRuntimeId, "id", "", "", "print pipeline runtime task logs by runtime id")
+	runtimeLogsCmd.PersistentFlags().StringVarP(&logsTaskId, "task", "", "", "print pipeline runtime task logs by task id")
+	runtimeLogsCmd.PersistentFlags().BoolVarP(&logsFollow, "follow", "f", false, "keep printing the logs until the task end")
+
+	runtimeApproveCmd.PersistentFlags().StringVarP(&pipelineRuntimeId, "id", "", "", "approve pipeline runtime task by runtime id")
+	runtimeApproveCmd.PersistentFlags().StringVarP(&approveTaskId, "task", "", "", "approve pipeline runtime task by task id")
+	runtimeApproveCmd.PersistentFlags().BoolVarP(&approveReject, "reject", "", false, "reject the approval task")
+	runtimeApproveCmd.PersistentFlags().StringVarP(&approveComment, "comment", "", "", "approval comment")
+
 	runtimeCmd.AddCommand(runtimeListCmd)
 	runtimeCmd.AddCommand(runtimeGetDetailCmd)
 	runtimeCmd.AddCommand(runtimeCancelCmd)
+	runtimeCmd.AddCommand(runtimeCleanCmd)
+	runtimeCmd.AddCommand(runtimeRerunCmd)
+	runtimeCmd.AddCommand(runtimePauseCmd)
+	runtimeCmd.AddCommand(runtimeResumeCmd)
+	runtimeCmd.AddCommand(runtimeLogsCmd)
+	runtimeCmd.AddCommand(runtimeApproveCmd)
 	return runtimeCmd
 }
@@ -0,0 +1,196 @@
+/*
+ * Copyright 2022 The kakj-go Authors.
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package webhook
+
+import (
+	"encoding/json"
+	"eventops/apistructs"
+	"eventops/internal/core/token"
+	"eventops/pkg/schema/event"
+	"eventops/tools/eoctl/conf"
+	"eventops/tools/eoctl/login"
+	"fmt"
+	"github.com/guonaihong/gout"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"os"
+	"time"
+)
+
+var applyFilePath string
+var deleteFilePath string
+
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "Operate webhook",
+	Long:  `You can perform a series of operations on the webhook`,
+	Run:   func(cmd *cobra.Command, args []string) {},
+}
+
+var webhookApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "apply webhook",
+	Long:  `Example: eoctl webhook apply -f webhook.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		applyUser := login.GetEditUserInfo()
+
+		content, err := os.ReadFile(applyFilePath)
+		if err != nil {
+			fmt.Printf("read file %v content error: %v \n", applyFilePath, err)
+			os.Exit(1)
+		}
+
+		url, err := applyWebhook(applyUser, string(content))
+		if err != nil {
+			fmt.Printf("apply webhook error: %v \n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("webhook url: %s%s \n", applyUser.Server, url)
+	},
+}
+
+var webhookDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "delete webhook",
+	Long:  `Example: eoctl webhook delete -f webhook.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		deleteUser := login.GetEditUserInfo()
+
+		content, err := os.ReadFile(deleteFilePath)
+		if err != nil {
+			fmt.Printf("read file %v content error: %v \n", deleteFilePath, err)
+			os.Exit(1)
+		}
+
+		var webhookInfo event.Webhook
+		err = yaml.Unmarshal(content, &webhookInfo)
+		if err != nil {
+			fmt.Printf("unmarshal file %v content error: %v \n", deleteFilePath, err)
+			os.Exit(1)
+		}
+		if webhookInfo.Name == "" {
+			fmt.Println("yaml content name can not empty")
+			os.Exit(1)
+		}
+		err = deleteWebhook(deleteUser, webhookInfo.Name)
+		if err != nil {
+			fmt.Printf("delete webhook error: %v \n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var webhookListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list my webhook",
+	Long:  `Example: eoctl webhook list`,
+	Run: func(cmd *cobra.Command, args []string) {
+		listUser := login.GetEditUserInfo()
+
+		webhooks, err := listMyWebhook(listUser)
+		if err != nil {
+			fmt.Printf("list my webhook error: %v \n", err)
+			os.Exit(1)
+		}
+		jsonValue, err := json.Marshal(webhooks)
+		if err != nil {
+			fmt.Printf("json marshal result error: %v \n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(jsonValue))
+	},
+}
+
+type Resp struct {
+	Status int
+	Msg    string
+	Data   interface{}
+}
+
+type ApplyWebhookResp struct {
+	Status int
+	Msg    string
+	Data   string
+}
+
+func applyWebhook(user *conf.UserInfo, content string) (string, error) {
+	var resp ApplyWebhookResp
+	err := gout.
+		POST(fmt.Sprintf("%s/%s", user.Server, "api/webhook/apply")).
+		SetHeader(gout.H{"sid": fmt.Sprintf("%x", time.Now().UnixNano()), token.AuthHeader: token.BuildTokenHeaderValue(user.Token)}).
+		SetJSON(gout.H{"webhookContent": content}).
+		BindJSON(&resp).
+		Do()
+	if err != nil {
+		return "", err
+	}
+	if resp.Status != 200 {
+		return "", fmt.Errorf("apply webhook status: %v, msg: %s", resp.Status, resp.Msg)
+	}
+	return resp.Data, nil
+}
+
+type ListMyWebhookResp struct {
+	Status int
+	Msg    string
+	Data   []apistructs.Webhook
+}
+
+func listMyWebhook(user *conf.UserInfo) ([]apistructs.Webhook, error) {
+	var resp ListMyWebhookResp
+	err := gout.
+		GET(fmt.Sprintf("%s/api/webhook/", user.Server)).
+		SetHeader(gout.H{"sid": fmt.Sprintf("%x", time.Now().UnixNano()), token.AuthHeader: token.BuildTokenHeaderValue(user.Token)}).
+		BindJSON(&resp).
+		Do()
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != 200 {
+		return nil, fmt.Errorf("list my webhook status: %v, msg: %s", resp.Status, resp.Msg)
+	}
+
+	return resp.Data, nil
+}
+
+func deleteWebhook(user *conf.UserInfo, name string) error {
+	var resp Resp
+	err := gout.
+		DELETE(fmt.Sprintf("%s/api/webhook/%s", user.Server, name)).
+		SetHeader(gout.H{"sid": fmt.Sprintf("%x", time.Now().UnixNano()), token.AuthHeader: token.BuildTokenHeaderValue(user.Token)}).
+		BindJSON(&resp).
+		Do()
+	if err != nil {
+		return err
+	}
+	if resp.Status != 200 {
+		return fmt.Errorf("delete webhook status: %v, msg: %s", resp.Status, resp.Msg)
+	}
+	return nil
+}
+
+func BuildWebhookCmd() *cobra.Command {
+	login.BindUserAndServerFlag(webhookCmd)
+	login.BindUserAndServerFlag(webhookApplyCmd)
+	login.BindUserAndServerFlag(webhookDeleteCmd)
+	login.BindUserAndServerFlag(webhookListCmd)
+
+	webhookApplyCmd.PersistentFlags().StringVarP(&applyFilePath, "f", "f", "", "webhook file location")
+	webhookDeleteCmd.PersistentFlags().StringVarP(&deleteFilePath, "f", "f", "", "webhook file location")
+
+	webhookCmd.AddCommand(webhookApplyCmd)
+	webhookCmd.AddCommand(webhookDeleteCmd)
+	webhookCmd.AddCommand(webhookListCmd)
+	return webhookCmd
+}
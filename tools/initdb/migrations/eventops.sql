@@ -65,6 +65,8 @@ CREATE TABLE `event_trigger_definitions`  (
   `event_name` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '事件名称',
   `event_version` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '事件版本',
   `event_creater` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '事件创建人',
+  `schedule` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '定时触发的 cron 表达式',
+  `schedule_last_time` datetime NULL DEFAULT NULL COMMENT '定时触发的上一次触发时间',
   PRIMARY KEY (`id`) USING BTREE
 ) ENGINE = InnoDB AUTO_INCREMENT = 6 CHARACTER SET = utf8mb4 COLLATE = utf8mb4_0900_ai_ci ROW_FORMAT = Dynamic;
 
@@ -106,7 +108,13 @@ CREATE TABLE `events`  (
   `content` mediumtext CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '事件内容',
   `status` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '状态',
   `status_message` varchar(500) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NULL DEFAULT NULL COMMENT '状态信息',
-  PRIMARY KEY (`id`) USING BTREE
+  `idempotency_key` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '幂等键',
+  `content_hash` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '去掉时间戳后的事件内容 hash',
+  `unique_idempotency_key` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci GENERATED ALWAYS AS (nullif(`idempotency_key`, '')) VIRTUAL NULL COMMENT '非空的幂等键, 用于唯一键',
+  PRIMARY KEY (`id`) USING BTREE,
+  INDEX `idx_creater_name_idempotency_key`(`creater`, `name`, `idempotency_key`) USING BTREE,
+  UNIQUE INDEX `uk_creater_name_idempotency_key`(`creater`, `name`, `unique_idempotency_key`) USING BTREE,
+  INDEX `idx_creater_name_content_hash`(`creater`, `name`, `content_hash`) USING BTREE
 ) ENGINE = InnoDB AUTO_INCREMENT = 188 CHARACTER SET = utf8mb4 COLLATE = utf8mb4_0900_ai_ci ROW_FORMAT = Dynamic;
 
 -- ----------------------------
@@ -163,9 +171,28 @@ CREATE TABLE `pipeline_tasks`  (
   `updated_at` datetime NOT NULL COMMENT '更新时间',
   `creater` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '创建者',
   `job_sign` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '执行环境的唯一标识',
+  `cleaned` tinyint(1) NOT NULL DEFAULT 0 COMMENT '执行器上的资源是否已回收',
   PRIMARY KEY (`id`) USING BTREE
 ) ENGINE = InnoDB AUTO_INCREMENT = 199 CHARACTER SET = utf8mb4 COLLATE = utf8mb4_0900_ai_ci ROW_FORMAT = Dynamic;
 
+-- ----------------------------
+-- Table structure for pipeline_task_logs
+-- ----------------------------
+DROP TABLE IF EXISTS `pipeline_task_logs`;
+CREATE TABLE `pipeline_task_logs`  (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `pipeline_id` bigint NOT NULL COMMENT '流水线id',
+  `task_id` bigint NOT NULL COMMENT '任务id',
+  `attempt` int NOT NULL DEFAULT 0 COMMENT '第几次重试, 第一次执行为 0',
+  `content` mediumtext CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '日志内容',
+  `truncated` tinyint(1) NOT NULL DEFAULT 0 COMMENT '日志是否被截断',
+  `creater` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '创建者',
+  `created_at` datetime NOT NULL COMMENT '创建时间',
+  `updated_at` datetime NOT NULL COMMENT '更新时间',
+  PRIMARY KEY (`id`) USING BTREE,
+  INDEX `idx_task_id`(`task_id`) USING BTREE
+) ENGINE = InnoDB AUTO_INCREMENT = 1 CHARACTER SET = utf8mb4 COLLATE = utf8mb4_0900_ai_ci ROW_FORMAT = Dynamic;
+
 -- ----------------------------
 -- Table structure for pipeline_version_definitions
 -- ----------------------------
@@ -204,7 +231,10 @@ CREATE TABLE `pipelines`  (
   `updated_at` datetime NOT NULL COMMENT '更新时间',
   `time_begin` datetime NULL DEFAULT NULL COMMENT '开始时间',
   `time_end` datetime NULL DEFAULT NULL COMMENT '结束时间',
-  PRIMARY KEY (`id`) USING BTREE
+  `concurrency_group` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL DEFAULT '' COMMENT '并发组',
+  `priority` int NOT NULL DEFAULT 0 COMMENT '优先级',
+  PRIMARY KEY (`id`) USING BTREE,
+  INDEX `idx_concurrency_group_status`(`concurrency_group`, `status`) USING BTREE
 ) ENGINE = InnoDB AUTO_INCREMENT = 195 CHARACTER SET = utf8mb4 COLLATE = utf8mb4_0900_ai_ci ROW_FORMAT = Dynamic;
 
 -- ----------------------------
@@ -220,7 +250,26 @@ CREATE TABLE `users`  (
   `updated_at` datetime NOT NULL COMMENT '更新时间',
   `deleted_at` datetime NULL DEFAULT NULL COMMENT '删除时间',
   `salt` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '盐',
+  `max_running_pipelines` int NOT NULL DEFAULT 0 COMMENT '同时运行的流水线的最大数量, 0 代表使用服务端的配置',
+  `schedule_weight` int NOT NULL DEFAULT 1 COMMENT '排队时的调度权重',
   PRIMARY KEY (`id`) USING BTREE
 ) ENGINE = InnoDB AUTO_INCREMENT = 6 CHARACTER SET = utf8mb4 COLLATE = utf8mb4_0900_ai_ci ROW_FORMAT = Dynamic;
 
+-- ----------------------------
+-- Table structure for webhooks
+-- ----------------------------
+DROP TABLE IF EXISTS `webhooks`;
+CREATE TABLE `webhooks`  (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `name` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '名称, 全局唯一',
+  `creater` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '创建者',
+  `provider` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '来源: github gitlab gitea',
+  `content` mediumtext CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci NOT NULL COMMENT '内容',
+  `created_at` datetime NOT NULL COMMENT '创建时间',
+  `updated_at` datetime NOT NULL COMMENT '更新时间',
+  `deleted_at` datetime NULL DEFAULT NULL COMMENT '删除时间',
+  PRIMARY KEY (`id`) USING BTREE,
+  UNIQUE INDEX `uk_name`(`name`) USING BTREE
+) ENGINE = InnoDB AUTO_INCREMENT = 1 CHARACTER SET = utf8mb4 COLLATE = utf8mb4_0900_ai_ci ROW_FORMAT = Dynamic;
+
 SET FOREIGN_KEY_CHECKS = 1;